@@ -0,0 +1,231 @@
+/*
+bus 是以 proto 描述分发值的事件总线.
+
+handler 是只有一个参数的函数, 可以返回 error, 例如
+
+	func(ev *example.com/x.Created)
+	func(ev io.Reader) error
+
+Publish(v) 把 v 分发给参数类型与 v 的动态类型相同, 或者参数是 v 实现了的接口的 handler.
+proto 描述只用于标识 handler, 描述相同而类型不同的值, 例如只有 tag 不同的匿名 struct, 不会被分发.
+同一个 handler 按 Publish 的顺序收到值, 多个 handler 按 Subscribe 的顺序执行.
+*/
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gohub/typeless/proto"
+	"reflect"
+	"sync"
+)
+
+func toInvalidHandler(s ...interface{}) error {
+	return errors.New("bus invalid handler: " + fmt.Sprint(s...))
+}
+func toHandlerPanic(s ...interface{}) error {
+	return errors.New("bus handler panic: " + fmt.Sprint(s...))
+}
+
+// handler 返回的错误, 以参数的 proto 描述标明来源, 可以用 errors.Is 判断原错误
+func toHandlerFailed(key string, err error) error {
+	return fmt.Errorf("bus handler %s: %w", key, err)
+}
+
+// 分发模式
+type Mode int
+
+const (
+	Sync  Mode = iota // Publish 在调用者的 goroutine 中依次执行 handler, 并返回 handler 的错误
+	Async             // Publish 立即返回, 每个 handler 在自己的 goroutine 中按顺序执行, 错误由 Wait 返回
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Bus 是事件总线, 可以并发使用
+type Bus struct {
+	mode     Mode
+	lock     sync.RWMutex
+	subs     []*Subscription // 按 Subscribe 的顺序
+	waitLock sync.Mutex
+	idle     *sync.Cond // pending 变为 0 时广播
+	pending  int        // Async 模式中未执行完毕的值
+	errs     []error    // Async 模式中收集的错误
+}
+
+// 返回指定模式的 Bus
+func New(mode Mode) *Bus {
+	b := &Bus{mode: mode}
+	b.idle = sync.NewCond(&b.waitLock)
+	return b
+}
+
+// 订阅的 handler, 用于取消订阅
+type Subscription struct {
+	bus   *Bus
+	key   string // 参数的 proto 描述
+	in    reflect.Type
+	fn    reflect.Value
+	lock  sync.Mutex
+	queue []reflect.Value // Async 模式中等待执行的值
+	busy  bool            // 是否有 goroutine 正在执行 queue
+	done  bool            // 已经取消订阅
+}
+
+// 参数的 proto 描述
+func (s *Subscription) Proto() string {
+	return s.key
+}
+
+// 订阅, handler 必须是 func(T) 或者 func(T) error
+func (b *Bus) Subscribe(handler interface{}) (*Subscription, error) {
+	fn := reflect.ValueOf(handler)
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return nil, toInvalidHandler(proto.Type(handler))
+	}
+	t := fn.Type()
+	if t.NumIn() != 1 || t.IsVariadic() || t.NumOut() > 1 || t.NumOut() == 1 && t.Out(0) != errorType {
+		return nil, toInvalidHandler(proto.Type(handler))
+	}
+	s := &Subscription{bus: b, key: proto.Type(t.In(0)), in: t.In(0), fn: fn}
+	b.lock.Lock()
+	b.subs = append(b.subs, s)
+	b.lock.Unlock()
+	return s, nil
+}
+
+// 取消订阅, Async 模式中尚未执行的值被丢弃.
+// 正在执行的值不会被中断, Unsubscribe 返回时 handler 可能仍在执行, 需要时用 Bus.Wait 等待.
+func (s *Subscription) Unsubscribe() {
+	b := s.bus
+	b.lock.Lock()
+	for i, x := range b.subs {
+		if x == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			break
+		}
+	}
+	b.lock.Unlock()
+
+	s.lock.Lock()
+	s.done = true
+	n := len(s.queue)
+	s.queue = nil
+	s.lock.Unlock()
+	if n != 0 {
+		b.done(n, nil)
+	}
+}
+
+// 判断 handler 是否接收类型为 t 的值
+func (s *Subscription) accepts(t reflect.Type) bool {
+	return t == s.in || s.in.Kind() == reflect.Interface && t.Implements(s.in)
+}
+
+// 分发 v. Sync 模式中返回全部 handler 的错误, handler 的 panic 也作为错误返回,
+// 错误都以 handler 参数的 proto 描述标明来源.
+// Async 模式总是返回 nil, 除非 v 是 nil.
+func (b *Bus) Publish(v interface{}) error {
+	if v == nil {
+		return errors.New("bus publish nil")
+	}
+	t := reflect.TypeOf(v)
+	val := reflect.ValueOf(v)
+
+	b.lock.RLock()
+	var subs []*Subscription
+	for _, s := range b.subs {
+		if s.accepts(t) {
+			subs = append(subs, s)
+		}
+	}
+	b.lock.RUnlock()
+
+	if b.mode == Async {
+		for _, s := range subs {
+			s.enqueue(val)
+		}
+		return nil
+	}
+	var errs []error
+	for _, s := range subs {
+		if err := s.call(val); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 执行 handler, panic 转换为 error
+func (s *Subscription) call(v reflect.Value) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = toHandlerPanic(s.key, ": ", e)
+		}
+	}()
+	out := s.fn.Call([]reflect.Value{v})
+	if len(out) == 1 && !out[0].IsNil() {
+		err = toHandlerFailed(s.key, out[0].Interface().(error))
+	}
+	return
+}
+
+// 放入队列, 没有 goroutine 在执行时启动一个
+func (s *Subscription) enqueue(v reflect.Value) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.done {
+		return
+	}
+	b := s.bus
+	b.waitLock.Lock()
+	b.pending++
+	b.waitLock.Unlock()
+	s.queue = append(s.queue, v)
+	if !s.busy {
+		s.busy = true
+		go s.run()
+	}
+}
+
+func (s *Subscription) run() {
+	for {
+		s.lock.Lock()
+		if len(s.queue) == 0 {
+			s.busy = false
+			s.lock.Unlock()
+			return
+		}
+		v := s.queue[0]
+		s.queue = s.queue[1:]
+		s.lock.Unlock()
+
+		s.bus.done(1, s.call(v))
+	}
+}
+
+// n 个值执行完毕或被丢弃, 记录 err
+func (b *Bus) done(n int, err error) {
+	b.waitLock.Lock()
+	defer b.waitLock.Unlock()
+	if err != nil {
+		b.errs = append(b.errs, err)
+	}
+	b.pending -= n
+	if b.pending == 0 {
+		b.idle.Broadcast()
+	}
+}
+
+// 等待 Async 模式中已经 Publish 的值全部执行完毕, 返回并清空期间收集的错误.
+// Sync 模式直接返回 nil. 可以与 Publish 并发调用, 此时也等待期间 Publish 的值.
+func (b *Bus) Wait() error {
+	b.waitLock.Lock()
+	defer b.waitLock.Unlock()
+	for b.pending != 0 {
+		b.idle.Wait()
+	}
+	err := errors.Join(b.errs...)
+	b.errs = nil
+	return err
+}
@@ -0,0 +1,172 @@
+package bus_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gohub/typeless/bus"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type created struct {
+	ID int
+}
+
+func subscribe(T *testing.T, b *bus.Bus, handler interface{}) *bus.Subscription {
+	s, err := b.Subscribe(handler)
+	if err != nil {
+		T.Fatal(err)
+	}
+	return s
+}
+
+func TestSync(T *testing.T) {
+	b := bus.New(bus.Sync)
+	var got []string
+	errRead := errors.New("read failed")
+	subscribe(T, b, func(ev *created) { got = append(got, "created") })
+	subscribe(T, b, func(r io.Reader) error {
+		got = append(got, "reader")
+		return errRead
+	})
+	subscribe(T, b, func(s string) { got = append(got, s) })
+	subscribe(T, b, func(ev *created) error { panic("boom") })
+
+	err := b.Publish(&created{ID: 1})
+	if err == nil || err.Error() != "bus handler panic: *github.com/gohub/typeless/bus_test.created: boom" {
+		T.Errorf("want the panic as error, got %v", err)
+	}
+	err = b.Publish(&bytes.Buffer{})
+	if err == nil || err.Error() != "bus handler io.Reader: read failed" || !errors.Is(err, errRead) {
+		T.Errorf("want read failed, got %v", err)
+	}
+	if err = b.Publish("s"); err != nil {
+		T.Error(err)
+	}
+	if err = b.Publish(1); err != nil {
+		T.Error(err)
+	}
+	want := []string{"created", "reader", "s"}
+	if !reflect.DeepEqual(got, want) {
+		T.Errorf("want %v, got %v", want, got)
+	}
+}
+
+// proto 描述相同而类型不同的值不会被分发
+func TestSameProto(T *testing.T) {
+	b := bus.New(bus.Sync)
+	n := 0
+	subscribe(T, b, func(v struct {
+		A int `json:"a"`
+	}) {
+		n++
+	})
+	if err := b.Publish(struct{ A int }{1}); err != nil || n != 0 {
+		T.Errorf("want no delivery, got %d %v", n, err)
+	}
+	if err := b.Publish(struct {
+		A int `json:"a"`
+	}{1}); err != nil || n != 1 {
+		T.Errorf("want one delivery, got %d %v", n, err)
+	}
+}
+
+func TestAsync(T *testing.T) {
+	b := bus.New(bus.Async)
+	var lock sync.Mutex
+	var ids []int
+	var total int
+	subscribe(T, b, func(ev created) {
+		lock.Lock()
+		ids = append(ids, ev.ID)
+		lock.Unlock()
+	})
+	sub := subscribe(T, b, func(ev created) error {
+		lock.Lock()
+		total += ev.ID
+		lock.Unlock()
+		if ev.ID == 3 {
+			return errors.New("three")
+		}
+		return nil
+	})
+	for i := 1; i <= 100; i++ {
+		b.Publish(created{ID: i})
+	}
+	if err := b.Wait(); err == nil || !strings.HasSuffix(err.Error(), ": three") {
+		T.Errorf("want three, got %v", err)
+	}
+	for i, id := range ids {
+		if id != i+1 {
+			T.Fatalf("want ordered delivery, got %v", ids)
+		}
+	}
+	if len(ids) != 100 || total != 5050 {
+		T.Errorf("want 100 events, got %d, %d", len(ids), total)
+	}
+	if err := b.Wait(); err != nil {
+		T.Errorf("errors must be cleared, got %v", err)
+	}
+
+	sub.Unsubscribe()
+	b.Publish(created{ID: 1})
+	b.Wait()
+	if total != 5050 {
+		T.Errorf("want no delivery after Unsubscribe, got %d", total)
+	}
+}
+
+// Wait 与 Publish, Unsubscribe 并发执行
+func TestAsyncConcurrent(T *testing.T) {
+	b := bus.New(bus.Async)
+	var lock sync.Mutex
+	n := 0
+	subs := make([]*bus.Subscription, 4)
+	for i := range subs {
+		subs[i] = subscribe(T, b, func(int) {
+			lock.Lock()
+			n++
+			lock.Unlock()
+		})
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.Publish(j)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			b.Wait()
+			subs[i].Unsubscribe()
+		}(i)
+	}
+	wg.Wait()
+	if err := b.Wait(); err != nil {
+		T.Error(err)
+	}
+	b.Publish(1)
+	if err := b.Wait(); err != nil || n > 4*400 {
+		T.Errorf("want at most %d calls, got %d %v", 4*400, n, err)
+	}
+}
+
+func TestSubscribeError(T *testing.T) {
+	b := bus.New(bus.Sync)
+	for _, h := range []interface{}{
+		nil, 1, func() {}, func(int, int) {}, func(int) int { return 0 }, func(...int) {},
+	} {
+		if _, err := b.Subscribe(h); err == nil {
+			T.Errorf("want an error for %T", h)
+		}
+	}
+	if err := b.Publish(nil); err == nil {
+		T.Error("want an error for nil")
+	}
+}
@@ -0,0 +1,47 @@
+/*
+typeless 是 typeless 工具集的命令行工具.
+
+用法
+
+	typeless <command> [arguments]
+
+命令
+
+	proto  以 proto 描述输出包的导出 API
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// 子命令, 返回值是进程的退出码
+var commands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"proto": protoCommand,
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `usage: typeless <command> [arguments]
+
+commands:
+	proto  print the exported API of a package in proto notation
+
+Use "typeless <command> -h" for more information about a command.
+`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "typeless: unknown command %q\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	os.Exit(cmd(os.Args[2:], os.Stdout, os.Stderr))
+}
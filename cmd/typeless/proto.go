@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/gohub/typeless/proto"
+	"github.com/gohub/typeless/proto/static"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// typeless proto [-json] [-pkg path] [dir]
+func protoCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("proto", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "output JSON, each declaration is encoded by proto.EncodeJSON")
+	pkgPath := fs.String("pkg", "", "import path of the package, default is computed from go.mod")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, "usage: typeless proto [-json] [-pkg path] [dir]\n\n"+
+			"Proto loads the Go package in dir (default \".\") from source and prints\n"+
+			"every exported func, method, struct and interface in proto notation.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	pkg, errs := loadPackage(dir, *pkgPath)
+	if pkg == nil {
+		fmt.Fprintln(stderr, "typeless proto:", errs[0])
+		return 1
+	}
+	// 类型错误不影响其他声明的输出
+	for _, err := range errs {
+		fmt.Fprintln(stderr, "typeless proto:", err)
+	}
+	api := describePackage(pkg)
+	w := bufio.NewWriter(stdout)
+	var err error
+	if *asJSON {
+		err = writeJSON(w, pkg.Path(), api)
+	} else {
+		writeText(w, pkg.Path(), api)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "typeless proto:", err)
+		return 1
+	}
+	return 0
+}
+
+// 以源代码加载 dir 中的包, 不包含测试文件. 只有无法加载时返回的包为 nil.
+func loadPackage(dir, path string) (*types.Package, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []error{err}
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, []error{err}
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, []error{fmt.Errorf("no Go files in %s", dir)}
+	}
+	if path == "" {
+		path = importPath(dir, files[0].Name.Name)
+	}
+	var errs []error
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(err error) { errs = append(errs, err) },
+	}
+	pkg, _ := conf.Check(path, fset, files, nil)
+	return pkg, errs
+}
+
+// 由 go.mod 中的 module 计算包的导入路径, 找不到 go.mod 时使用包名
+func importPath(dir, name string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return name
+	}
+	for d := abs; ; {
+		if b, err := os.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+			if module := modulePath(b); module != "" {
+				rel, err := filepath.Rel(d, abs)
+				if err != nil || rel == "." {
+					return module
+				}
+				return module + "/" + filepath.ToSlash(rel)
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return name
+		}
+		d = parent
+	}
+}
+
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") || strings.HasPrefix(line, "module\t") {
+			return strings.Trim(strings.TrimSpace(line[len("module"):]), `"`)
+		}
+	}
+	return ""
+}
+
+// 包中导出的声明. 函数的 Underlying 为空,
+// 类型声明的 Underlying 是底层类型, Methods 是按名称排序的方法集.
+type decl struct {
+	proto.ProtoType
+	Name       string
+	Underlying string
+	Methods    []proto.Fn
+}
+
+// 返回包中导出的函数和类型的描述, 按名称排序
+func describePackage(pkg *types.Package) []decl {
+	scope := pkg.Scope()
+	var api []decl
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Func:
+			fn := static.Describe(obj.Type()).(*proto.Fn)
+			fn.Name = obj.Name()
+			api = append(api, decl{ProtoType: fn, Name: obj.Name()})
+		case *types.TypeName:
+			if obj.IsAlias() {
+				continue
+			}
+			t := obj.Type()
+			api = append(api, decl{static.Describe(t), obj.Name(), static.Type(t.Underlying()), static.Methods(t)})
+		}
+	}
+	return api
+}
+
+// 文本格式, 例如
+//
+//	package example.com/m
+//
+//	func Handle(net/http.ResponseWriter, *net/http.Request)
+//
+//	type ID int
+//		func String() string
+//
+//	type User struct
+//		Name string "json:\"name\""
+//		func SetName(string) error
+func writeText(w io.Writer, path string, api []decl) {
+	fmt.Fprintf(w, "package %s\n", path)
+	for _, d := range api {
+		fmt.Fprintln(w)
+		switch p := d.ProtoType.(type) {
+		case *proto.Struct:
+			fmt.Fprintf(w, "type %s struct\n", d.Name)
+			for i := range p.Fields {
+				if p.Fields[i].Exported {
+					fmt.Fprintf(w, "\t%s\n", p.Fields[i].String())
+				}
+			}
+		case *proto.Interface:
+			fmt.Fprintf(w, "type %s interface\n", d.Name)
+		default:
+			if d.Underlying == "" {
+				fmt.Fprintln(w, p.String())
+				continue
+			}
+			fmt.Fprintf(w, "type %s %s\n", d.Name, d.Underlying)
+		}
+		for i := range d.Methods {
+			fmt.Fprintf(w, "\t%s\n", d.Methods[i].String())
+		}
+	}
+}
+
+// JSON 格式, 每个声明都可以由 proto.DecodeJSON 解码
+//
+//	{"package":"example.com/m","decls":[{"version":1,"kind":"func","desc":{...}}]}
+func writeJSON(w io.Writer, path string, api []decl) error {
+	decls := make([]json.RawMessage, len(api))
+	for i, d := range api {
+		b, err := proto.EncodeJSON(d.ProtoType)
+		if err != nil {
+			return err
+		}
+		decls[i] = b
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(struct {
+		Package string            `json:"package"`
+		Decls   []json.RawMessage `json:"decls"`
+	}{path, decls})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gohub/typeless/proto"
+	"testing"
+)
+
+func TestProtoText(T *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := protoCommand([]string{"-pkg", "example.com/api", "testdata/api"}, &stdout, &stderr); code != 0 {
+		T.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	want := `package example.com/api
+
+func Handle(net/http.ResponseWriter, *net/http.Request)
+
+type Handler interface
+	func ServeHTTP(net/http.ResponseWriter, *net/http.Request)
+
+type HandlerFunc func(net/http.ResponseWriter, *net/http.Request)
+	func ServeHTTP(net/http.ResponseWriter, *net/http.Request)
+
+type ID int
+	func String() string
+
+type User struct
+	Name string "json:\"name\""
+	func SetName(string) error
+`
+	if got := stdout.String(); got != want {
+		T.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestProtoJSON(T *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := protoCommand([]string{"-json", "-pkg", "example.com/api", "testdata/api"}, &stdout, &stderr); code != 0 {
+		T.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	var out struct {
+		Package string
+		Decls   []json.RawMessage
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		T.Fatal(err)
+	}
+	want := []string{
+		"func Handle(net/http.ResponseWriter, *net/http.Request)",
+		"example.com/api.Handler",
+		"type HandlerFunc func(net/http.ResponseWriter, *net/http.Request)",
+		"example.com/api.ID",
+		"example.com/api.User",
+	}
+	if out.Package != "example.com/api" || len(out.Decls) != len(want) {
+		T.Fatalf("unexpected output: %s", stdout.String())
+	}
+	for i, b := range out.Decls {
+		p, err := proto.DecodeJSON(b)
+		if err != nil {
+			T.Fatal(err)
+		}
+		if got := p.String(); got != want[i] {
+			T.Errorf("decl %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestProtoNoFiles(T *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := protoCommand([]string{T.TempDir()}, &stdout, &stderr); code != 1 {
+		T.Errorf("exit %d, want 1", code)
+	}
+}
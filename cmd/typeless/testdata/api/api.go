@@ -0,0 +1,26 @@
+package api
+
+import "net/http"
+
+type Handler interface {
+	ServeHTTP(http.ResponseWriter, *http.Request)
+}
+
+type User struct {
+	Name string `json:"name"`
+	age  int
+}
+
+func (u *User) SetName(name string) error { return nil }
+
+type ID int
+
+func (id ID) String() string { return "" }
+
+type HandlerFunc func(http.ResponseWriter, *http.Request)
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) { f(w, r) }
+
+func Handle(w http.ResponseWriter, r *http.Request) {}
+
+func unexported() {}
@@ -0,0 +1,135 @@
+package proto
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Bag 是以 proto 描述为键的值容器, 同一类型可以有多个值, 保持 Put 的顺序.
+// 值以其动态类型的 proto 描述为键, 实现了 error 的值也是如此, 可以用 Errors 获取.
+// 零值是空的 Bag, 可以并发使用.
+type Bag struct {
+	lock sync.RWMutex
+	m    map[string][]interface{}
+	vals []interface{} // 全部的值, 按 Put 的顺序
+}
+
+// 放入值, nil 会抛出 panic
+func (b *Bag) Put(vs ...interface{}) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.m == nil {
+		b.m = map[string][]interface{}{}
+	}
+	for _, v := range vs {
+		if v == nil {
+			panic("proto bag put nil")
+		}
+		key := bagKey(v)
+		b.m[key] = append(b.m[key], v)
+		b.vals = append(b.vals, v)
+	}
+}
+
+// 值的键, 与 Type 不同, error 也使用其动态类型
+func bagKey(v interface{}) string {
+	return prototype(reflect.TypeOf(v))
+}
+
+// 把与 *target 类型相同的第一个值赋给 *target, 没有时使用第一个可以赋值的值,
+// 例如放入 *bytes.Buffer, 以 *io.Reader 获取. target 必须是非 nil 指针.
+// 找不到时返回 false.
+func (b *Bag) Get(target interface{}) bool {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic("proto bag invalid target: " + prototype(target))
+	}
+	v = v.Elem()
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if vs := b.m[prototype(v.Type())]; len(vs) != 0 {
+		if x := reflect.ValueOf(vs[0]); x.Type().AssignableTo(v.Type()) {
+			v.Set(x)
+			return true
+		}
+	}
+	for _, x := range b.vals {
+		if x := reflect.ValueOf(x); x.Type().AssignableTo(v.Type()) {
+			v.Set(x)
+			return true
+		}
+	}
+	return false
+}
+
+// 返回 proto 描述为 s 的第一个值
+func (b *Bag) GetByProto(s string) (interface{}, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if vs := b.m[s]; len(vs) != 0 {
+		return vs[0], true
+	}
+	return nil, false
+}
+
+// 返回 proto 描述为 s 的全部值, 按 Put 的顺序
+func (b *Bag) All(s string) []interface{} {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return append([]interface{}(nil), b.m[s]...)
+}
+
+// 返回可以赋值给类型 t 的全部值, 按 Put 的顺序. t 可以是值或者 reflect.Type,
+// 接口类型需要以 reflect.Type 形式给出. t 为 nil 时返回 nil.
+func (b *Bag) Find(t interface{}) []interface{} {
+	rt := TypeOf(t)
+	if rt == nil {
+		return nil
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	var found []interface{}
+	for _, x := range b.vals {
+		if reflect.TypeOf(x).AssignableTo(rt) {
+			found = append(found, x)
+		}
+	}
+	return found
+}
+
+// 返回实现了 error 的全部值, 按 Put 的顺序
+func (b *Bag) Errors() []error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	var errs []error
+	for _, x := range b.vals {
+		if err, ok := x.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// 删除 proto 描述为 s 的全部值
+func (b *Bag) Delete(s string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.m[s]) == 0 {
+		return
+	}
+	delete(b.m, s)
+	vals := b.vals[:0]
+	for _, x := range b.vals {
+		if bagKey(x) != s {
+			vals = append(vals, x)
+		}
+	}
+	b.vals = vals
+}
+
+// 值的总数
+func (b *Bag) Len() int {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return len(b.vals)
+}
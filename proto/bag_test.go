@@ -0,0 +1,111 @@
+package proto_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gohub/typeless/proto"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestBag(T *testing.T) {
+	b := &proto.Bag{}
+	buf := &bytes.Buffer{}
+	err := errors.New("x")
+	b.Put(1, "a", buf, 2, err)
+
+	var n int
+	if !b.Get(&n) || n != 1 {
+		T.Errorf("want 1, got %v", n)
+	}
+	var r io.Reader
+	if !b.Get(&r) || r != buf {
+		T.Errorf("want the buffer, got %v", r)
+	}
+	var e error
+	if !b.Get(&e) || e != err {
+		T.Errorf("want %v, got %v", err, e)
+	}
+	var p *os.PathError
+	if b.Get(&p) {
+		T.Error("want false for *os.PathError")
+	}
+	var f float64
+	if b.Get(&f) {
+		T.Error("want false for float64")
+	}
+	if v, ok := b.GetByProto("*bytes.Buffer"); !ok || v != buf {
+		T.Errorf("want the buffer, got %v", v)
+	}
+	if got := b.All("int"); !reflect.DeepEqual(got, []interface{}{1, 2}) {
+		T.Errorf("want [1 2], got %v", got)
+	}
+	if got := b.Find(proto.TypeIndirect((*io.Writer)(nil))); len(got) != 1 || got[0] != buf {
+		T.Errorf("want the buffer, got %v", got)
+	}
+	if got := b.Find(nil); got != nil {
+		T.Errorf("want nil, got %v", got)
+	}
+	b.Delete("int")
+	if b.Len() != 3 {
+		T.Errorf("want 3 values, got %d", b.Len())
+	}
+	if b.Get(&n) {
+		T.Error("want int deleted")
+	}
+}
+
+// 不同的 error 以动态类型区分
+func TestBagErrors(T *testing.T) {
+	b := &proto.Bag{}
+	x, y := errors.New("x"), &os.PathError{Op: "open", Err: io.EOF}
+	b.Put(x, 1, y)
+	if got := b.Errors(); !reflect.DeepEqual(got, []error{x, y}) {
+		T.Errorf("want [x y], got %v", got)
+	}
+	if got := b.All("error"); len(got) != 0 {
+		T.Errorf("want no values under error, got %v", got)
+	}
+	if v, ok := b.GetByProto("*io/fs.PathError"); !ok || v != y {
+		T.Errorf("want the path error, got %v", v)
+	}
+	var p *os.PathError
+	if !b.Get(&p) || p != y {
+		T.Errorf("want the path error, got %v", p)
+	}
+	b.Delete("*errors.errorString")
+	if got := b.Errors(); len(got) != 1 || got[0] != y {
+		T.Errorf("want [y], got %v", got)
+	}
+}
+
+func TestBagConcurrent(T *testing.T) {
+	b := &proto.Bag{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b.Put(i)
+			var n int
+			b.Get(&n)
+			b.All("int")
+		}(i)
+	}
+	wg.Wait()
+	if b.Len() != 8 {
+		T.Errorf("want 8 values, got %d", b.Len())
+	}
+}
+
+func TestBagInvalidTarget(T *testing.T) {
+	defer func() {
+		if recover() == nil {
+			T.Error("want a panic")
+		}
+	}()
+	(&proto.Bag{}).Get(1)
+}
@@ -0,0 +1,156 @@
+package proto
+
+import (
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// 复制函数, 返回与 v 类型相同的副本. v 不是可寻址的.
+type CloneFunc func(v reflect.Value) reflect.Value
+
+var (
+	cloneLock  sync.RWMutex
+	cloneHooks = map[string]CloneFunc{}
+)
+
+func init() {
+	// 值语义的类型直接复制
+	same := func(v reflect.Value) reflect.Value { return v }
+	// 不能复制的类型使用零值
+	zero := func(v reflect.Value) reflect.Value { return reflect.Zero(v.Type()) }
+	RegisterClone(prototype(time.Time{}), same)
+	for _, x := range []interface{}{
+		sync.Mutex{}, sync.RWMutex{}, sync.WaitGroup{}, sync.Once{},
+	} {
+		RegisterClone(prototype(x), zero)
+	}
+}
+
+// 为 proto 描述为 s 的类型注册复制函数, fn 为 nil 时删除.
+// 已经注册了 time.Time 直接复制, sync.Mutex, sync.RWMutex, sync.WaitGroup,
+// sync.Once 复制为零值.
+func RegisterClone(s string, fn CloneFunc) {
+	cloneLock.Lock()
+	defer cloneLock.Unlock()
+	if fn == nil {
+		delete(cloneHooks, s)
+	} else {
+		cloneHooks[s] = fn
+	}
+}
+
+func cloneHook(t reflect.Type) CloneFunc {
+	cloneLock.RLock()
+	defer cloneLock.RUnlock()
+	if len(cloneHooks) == 0 {
+		return nil
+	}
+	return cloneHooks[prototype(t)]
+}
+
+// 深度复制 v, 包括非导出字段. 指向同一对象的指针, map, slice 在副本中仍然指向同一对象,
+// 因此循环引用也会被复制. func, chan, unsafe.Pointer 不复制, 副本与原值相同.
+// 注册了 CloneFunc 的类型由 CloneFunc 复制.
+func Clone(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	c := &cloner{m: map[cloneKey]reflect.Value{}}
+	return c.clone(reflect.ValueOf(v)).Interface()
+}
+
+// 引用的键, slice 还需要长度和容量
+type cloneKey struct {
+	visit
+	len, cap int
+}
+
+type cloner struct {
+	m map[cloneKey]reflect.Value // 已经复制的引用
+}
+
+func (c *cloner) clone(v reflect.Value) reflect.Value {
+	t := v.Type()
+	if fn := cloneHook(t); fn != nil {
+		return fn(v)
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		x := reflect.New(t).Elem()
+		x.Set(c.clone(v.Elem()))
+		return x
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		key := cloneKey{visit: visit{v.Pointer(), t}}
+		if x, ok := c.m[key]; ok {
+			return x
+		}
+		x := reflect.New(t.Elem())
+		c.m[key] = x
+		x.Elem().Set(c.clone(v.Elem()))
+		return x
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		key := cloneKey{visit: visit{v.Pointer(), t}}
+		if x, ok := c.m[key]; ok {
+			return x
+		}
+		x := reflect.MakeMapWithSize(t, v.Len())
+		c.m[key] = x
+		iter := v.MapRange()
+		for iter.Next() {
+			x.SetMapIndex(c.clone(iter.Key()), c.clone(iter.Value()))
+		}
+		return x
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		key := cloneKey{visit{v.Pointer(), t}, v.Len(), v.Cap()}
+		if x, ok := c.m[key]; ok {
+			return x
+		}
+		x := reflect.MakeSlice(t, v.Len(), v.Cap())
+		c.m[key] = x
+		for i := 0; i < v.Len(); i++ {
+			x.Index(i).Set(c.clone(v.Index(i)))
+		}
+		return x
+	case reflect.Array:
+		x := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			x.Index(i).Set(c.clone(v.Index(i)))
+		}
+		return x
+	case reflect.Struct:
+		// 非导出字段通过 unsafe 读写, 需要可寻址的值
+		if !v.CanAddr() {
+			x := reflect.New(t).Elem()
+			x.Set(v)
+			v = x
+		}
+		x := reflect.New(t).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			if !f.CanInterface() {
+				f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			}
+			dst := x.Field(i)
+			if !dst.CanSet() {
+				dst = reflect.NewAt(dst.Type(), unsafe.Pointer(dst.UnsafeAddr())).Elem()
+			}
+			dst.Set(c.clone(f))
+		}
+		return x
+	}
+	return v
+}
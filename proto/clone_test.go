@@ -0,0 +1,73 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type account struct {
+	mu      sync.Mutex
+	Owner   *User
+	Backup  *User
+	Created time.Time
+	balance map[string][]int
+	Any     interface{}
+	Self    *account
+	fn      func() int
+}
+
+func TestClone(T *testing.T) {
+	u := &User{Name: "a", Tags: []string{"x"}, email: "e"}
+	a := &account{
+		Owner: u, Backup: u,
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		balance: map[string][]int{"usd": {1, 2}},
+		Any:     [2]*User{u, nil},
+		fn:      func() int { return 1 },
+	}
+	a.Self = a
+	a.mu.Lock()
+	c := proto.Clone(a).(*account)
+
+	if !reflect.DeepEqual(c.Owner, u) || c.Owner == u {
+		T.Errorf("want a deep copy of Owner, got %#v", c.Owner)
+	}
+	if c.Backup != c.Owner || c.Any.([2]*User)[0] != c.Owner {
+		T.Error("shared pointers must stay shared")
+	}
+	if c.Self != c {
+		T.Error("cycle must point to the copy")
+	}
+	if !c.Created.Equal(a.Created) || c.Created.Location() != time.UTC {
+		T.Errorf("want %v, got %v", a.Created, c.Created)
+	}
+	if !c.mu.TryLock() {
+		T.Error("mutex must be copied as zero value")
+	}
+	if c.fn() != 1 {
+		T.Error("func must be kept")
+	}
+	c.balance["usd"][0] = 9
+	c.Owner.Tags[0] = "y"
+	if a.balance["usd"][0] != 1 || u.Tags[0] != "x" || c.Owner.email != "e" {
+		T.Error("copy must not share maps and slices with the original")
+	}
+	if proto.Clone(nil) != nil || proto.Clone(1) != 1 {
+		T.Error("want basic values unchanged")
+	}
+}
+
+func TestRegisterClone(T *testing.T) {
+	s := proto.Type(Base{})
+	proto.RegisterClone(s, func(v reflect.Value) reflect.Value {
+		return reflect.ValueOf(Base{ID: -1})
+	})
+	defer proto.RegisterClone(s, nil)
+	c := proto.Clone(&User{Base: Base{ID: 1}}).(*User)
+	if c.ID != -1 {
+		T.Errorf("want the hook to copy Base, got %d", c.ID)
+	}
+}
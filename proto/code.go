@@ -0,0 +1,378 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func toCodeFailed(s ...interface{}) error {
+	return errors.New("proto code failed: " + fmt.Sprint(s...))
+}
+
+// 由 PkgPath 推测包名, 去掉版本后缀和常见的 go- 前缀, 比如
+//
+//	gopkg.in/yaml.v2     yaml
+//	example.com/x/v2     x
+//	github.com/a/go-json json
+func packageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "go-"), "-go")
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9' && len(b) != 0, c == '_':
+		case c >= 'A' && c <= 'Z':
+			c += 'a' - 'A'
+		default:
+			continue
+		}
+		b = append(b, c)
+	}
+	if len(b) == 0 {
+		return "pkg"
+	}
+	return string(b)
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// Imports 是生成代码时的 import 集合, 为 PkgPath 分配不冲突的包名.
+// 当前包 Pkg 中的类型不需要 import.
+type Imports struct {
+	Pkg   string
+	paths map[string]string // PkgPath -> 包名
+	names map[string]string // 包名 -> PkgPath
+}
+
+// 生成 Imports, pkg 是生成代码所在包的 PkgPath
+func NewImports(pkg string) *Imports {
+	return &Imports{Pkg: pkg, paths: map[string]string{}, names: map[string]string{}}
+}
+
+// 返回 PkgPath 在代码中使用的包名, 必要时添加 import. 当前包返回空字符串.
+func (im *Imports) Qualify(path string) string {
+	if path == "" || path == im.Pkg {
+		return ""
+	}
+	if name, ok := im.paths[path]; ok {
+		return name
+	}
+	name := packageName(path)
+	if _, ok := im.names[name]; ok {
+		// 冲突时先尝试加上上一级目录名, 比如 math/rand 为 mathrand
+		elems := strings.Split(path, "/")
+		if len(elems) > 1 {
+			name = packageName(strings.Join(elems[:len(elems)-1], "/")) + name
+		}
+		for i, base := 2, name; ; i++ {
+			if _, ok := im.names[name]; !ok {
+				break
+			}
+			name = base + strconv.Itoa(i)
+		}
+	}
+	im.paths[path] = name
+	im.names[name] = path
+	return name
+}
+
+// 把 proto 描述转换为 Go 代码中的类型写法, 比如 *net/http.Request 转换为 *http.Request.
+// 不能解析的 proto 原样返回.
+func (im *Imports) Type(proto string) string {
+	x, err := Parse(proto)
+	if err != nil {
+		return proto
+	}
+	return string(x.appendTo(nil, im.Qualify, true))
+}
+
+// 返回 import 代码块, 没有 import 时返回空字符串
+func (im *Imports) Code() string {
+	if len(im.paths) == 0 {
+		return ""
+	}
+	paths := make([]string, 0, len(im.paths))
+	for path := range im.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	lines := make([]string, len(paths))
+	for i, path := range paths {
+		lines[i] = strconv.Quote(path)
+		if name := im.paths[path]; name != path[strings.LastIndexByte(path, '/')+1:] {
+			lines[i] = name + " " + lines[i]
+		}
+	}
+	if len(lines) == 1 {
+		return "import " + lines[0] + "\n\n"
+	}
+	return "import (\n\t" + strings.Join(lines, "\n\t") + "\n)\n\n"
+}
+
+// 生成代码的描述, r 用于查找 struct 嵌入字段的类型和命名类型的底层类型
+type declarer interface {
+	decl(im *Imports, r *Registry, name string) (string, error)
+}
+
+// 生成完整的 Go 源文件, pkg 是所在包的 PkgPath.
+// 嵌入字段的类型由默认注册表查找, 见 Registry.Source.
+func Source(pkg string, ps ...ProtoType) (string, error) {
+	return Default.Source(pkg, ps...)
+}
+
+// 同 Source, struct 嵌入字段的类型和命名类型的底层类型由 r 查找, 以排除通过嵌入字段获得的方法.
+// 不支持生成代码, 没有名称, 泛型实例的描述, 以及生成的代码不能通过 gofmt 时返回 error.
+func (r *Registry) Source(pkg string, ps ...ProtoType) (string, error) {
+	im := NewImports(pkg)
+	decls := ""
+	for _, p := range ps {
+		d, ok := p.(declarer)
+		if !ok {
+			return "", toCodeFailed("unsupported ", p)
+		}
+		s, err := d.decl(im, r, "")
+		if err != nil {
+			return "", err
+		}
+		decls += "\n" + s
+	}
+	name := "main"
+	if pkg != "" {
+		name = packageName(pkg)
+	}
+	return formatCode("package " + name + "\n\n" + im.Code() + decls)
+}
+
+// 生成代码, 包含 import 代码块, 当前包为描述所在的包. 不能生成代码时返回空字符串.
+func code(d declarer, proto, name string) string {
+	im := NewImports(pkgPathOf(proto))
+	s, err := d.decl(im, Default, name)
+	if err != nil {
+		return ""
+	}
+	s, err = formatCode(im.Code() + s)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+func formatCode(src string) (string, error) {
+	b, err := format.Source([]byte(src))
+	if err != nil {
+		return "", toCodeFailed(err)
+	}
+	return string(b), nil
+}
+
+// 类型声明的名称, 泛型实例缺少类型参数的声明, 不能生成代码
+func declName(name string, t *T) (string, error) {
+	if name == "" {
+		name = t.Name
+	}
+	if name == "" {
+		return "", toCodeFailed("unnamed ", t.Proto)
+	}
+	if strings.IndexByte(name, '[') != -1 {
+		return "", toCodeFailed("generic instantiation ", t.Proto)
+	}
+	return name, nil
+}
+
+// 命名类型 proto 的 PkgPath
+func pkgPathOf(proto string) string {
+	x, err := Parse(proto)
+	if err != nil || x.Kind != KindIdent {
+		return ""
+	}
+	return x.PkgPath
+}
+
+func firstName(names []string, name string) string {
+	if len(names) != 0 {
+		return names[0]
+	}
+	return name
+}
+
+// 生成变量声明, 参数为变量名. 描述命名类型而未给出变量名时生成类型声明, 例如 type IDs []int,
+// 底层类型由默认注册表查找.
+func (t *T) Code(name ...string) string {
+	if t.Proto == "" {
+		if t.Type == "" {
+			return ""
+		}
+		if len(name) != 0 {
+			return "var " + strings.Join(name, ", ") + " " + t.Type
+		}
+		if t.Name == "" {
+			return ""
+		}
+		return "var " + t.Name + " " + t.Type
+	}
+	if len(name) == 0 && t.Type == "" {
+		return code(t, t.Proto, "")
+	}
+	return code(t, "", strings.Join(name, ", "))
+}
+
+func (t *T) decl(im *Imports, r *Registry, name string) (string, error) {
+	if name != "" || t.Type != "" {
+		if name == "" {
+			name = t.Name
+		}
+		if name == "" {
+			return "", toCodeFailed("unnamed ", t.Proto)
+		}
+		return "var " + name + " " + im.Type(t.Proto) + "\n", nil
+	}
+	name, err := declName(name, t)
+	if err != nil {
+		return "", err
+	}
+	rt, err := r.Lookup(t.Proto)
+	if err != nil {
+		return "", toCodeFailed(err)
+	}
+	u := underlying(rt)
+	if u == "" || rt.PkgPath() == "" {
+		return "", toCodeFailed("unsupported ", t.Proto)
+	}
+	return "type " + name + " " + im.Type(u) + "\n", nil
+}
+
+// 生成函数桩代码, 参数为函数名. 命名的函数类型生成类型声明, 参数为类型名.
+func (f *Fn) Code(name ...string) string {
+	if f.named() {
+		return code(f, f.Proto, firstName(name, f.Name))
+	}
+	return code(f, "", firstName(name, f.Name))
+}
+
+func (f *Fn) decl(im *Imports, r *Registry, name string) (string, error) {
+	name, err := declName(name, &f.T)
+	if err != nil {
+		return "", err
+	}
+	if f.named() {
+		return "type " + name + " func" + f.codeSignature(im, false) + "\n", nil
+	}
+	return "func " + name + f.codeSignature(im, true) + " {\n\tpanic(\"not implemented\")\n}\n", nil
+}
+
+// 代码形式的签名, named 表示是否为参数命名
+func (f *Fn) codeSignature(im *Imports, named bool) string {
+	s := "("
+	for i, t := range f.In {
+		if i != 0 {
+			s += ", "
+		}
+		if named {
+			s += "a" + strconv.Itoa(i) + " "
+		}
+		if f.Variadic && i == len(f.In)-1 {
+			s += "..." + im.Type(strings.TrimPrefix(t.Proto, "[]"))
+		} else {
+			s += im.Type(t.Proto)
+		}
+	}
+	s += ")"
+	if len(f.Out) == 1 {
+		return s + " " + im.Type(f.Out[0].Proto)
+	}
+	if len(f.Out) > 1 {
+		s += " ("
+		for i, t := range f.Out {
+			if i != 0 {
+				s += ", "
+			}
+			s += im.Type(t.Proto)
+		}
+		s += ")"
+	}
+	return s
+}
+
+// 生成接口声明, 参数为类型名
+func (i *Interface) Code(name ...string) string {
+	return code(i, i.Proto, firstName(name, i.Name))
+}
+
+func (i *Interface) decl(im *Imports, r *Registry, name string) (string, error) {
+	name, err := declName(name, &i.T)
+	if err != nil {
+		return "", err
+	}
+	if len(i.Methods) == 0 {
+		return "type " + name + " interface{}\n", nil
+	}
+	s := "type " + name + " interface {\n"
+	for _, m := range methodNames(i.Methods) {
+		fn := i.Methods[m]
+		s += "\t" + m + fn.codeSignature(im, false) + "\n"
+	}
+	return s + "}\n", nil
+}
+
+// 生成 struct 声明和方法桩代码, 参数为类型名.
+// 方法的接收者都是指针, 通过嵌入字段获得的方法只有在嵌入类型已在默认注册表中注册时才能被排除,
+// 使用其他注册表时见 Registry.Source.
+func (s *Struct) Code(name ...string) string {
+	return code(s, s.Proto, firstName(name, s.Name))
+}
+
+func (s *Struct) decl(im *Imports, r *Registry, name string) (string, error) {
+	name, err := declName(name, &s.T)
+	if err != nil {
+		return "", err
+	}
+	src := "type " + name + " struct {\n"
+	promoted := map[string]bool{}
+	for _, f := range s.Fields {
+		src += "\t"
+		if !f.Embedded {
+			src += f.Name + " "
+		} else if t, err := r.Lookup(f.Proto); err == nil {
+			for m := range methods(TypeIndirect(t)) {
+				promoted[m] = true
+			}
+		}
+		src += im.Type(f.Proto)
+		if f.Tag != "" {
+			if strings.IndexByte(f.Tag, '`') == -1 {
+				src += " `" + f.Tag + "`"
+			} else {
+				src += " " + strconv.Quote(f.Tag)
+			}
+		}
+		src += "\n"
+	}
+	src += "}\n"
+	for _, m := range methodNames(s.Methods) {
+		if promoted[m] {
+			continue
+		}
+		fn := s.Methods[m]
+		src += "\nfunc (p *" + name + ") " + m + fn.codeSignature(im, true) +
+			" {\n\tpanic(\"not implemented\")\n}\n"
+	}
+	return src, nil
+}
@@ -0,0 +1,158 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+type Handler interface {
+	Serve(w http.ResponseWriter, r *http.Request) error
+	Rand() (*rand.Rand, io.Reader)
+	Template() *template.Template
+}
+
+func TestStructCode(T *testing.T) {
+	r := &proto.Registry{}
+	r.Register(Base{})
+	got, err := r.Source("github.com/gohub/typeless/proto_test", proto.Describe(User{}))
+	want := `package proto_test
+
+type User struct {
+	Base
+	Name  string ` + "`json:\"name\"`" + `
+	Tags  []string
+	email string
+}
+
+func (p *User) SetName(a0 string, a1 ...string) error {
+	panic("not implemented")
+}
+`
+	if got != want || err != nil {
+		T.Errorf("want:\n%s\ngot:\n%s %v", want, got, err)
+	}
+
+	// Base 未注册时不能排除通过嵌入字段获得的方法
+	got, err = (&proto.Registry{}).Source("", proto.Describe(User{}))
+	if err != nil || !strings.Contains(got, "func (p *User) Key() int {") {
+		T.Errorf("want method Key, got:\n%s %v", got, err)
+	}
+}
+
+func TestSource(T *testing.T) {
+	for _, c := range []struct {
+		ps   []proto.ProtoType
+		want string
+	}{
+		{
+			[]proto.ProtoType{
+				proto.Describe(proto.TypeIndirect((*Handler)(nil))),
+				&proto.T{Name: "page", Type: "*template.Template", Proto: "*html/template.Template"},
+			},
+			`package gen
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"math/rand"
+	"net/http"
+	"text/template"
+)
+
+type Handler interface {
+	Rand() (*rand.Rand, io.Reader)
+	Serve(http.ResponseWriter, *http.Request) error
+	Template() *template.Template
+}
+
+var page *htmltemplate.Template
+`,
+		},
+		{
+			[]proto.ProtoType{proto.Describe(proto.TypeIndirect((*io.ReadWriter)(nil)))},
+			`package gen
+
+type ReadWriter interface {
+	Read([]uint8) (int, error)
+	Write([]uint8) (int, error)
+}
+`,
+		},
+	} {
+		got, err := proto.Source("example.com/gen", c.ps...)
+		if got != c.want || err != nil {
+			T.Errorf("want:\n%s\ngot:\n%s %v", c.want, got, err)
+		}
+	}
+
+	write := proto.Describe(http.Request{}).(*proto.Struct).Methods["Write"]
+	want := `import "io"
+
+func Write(a0 io.Writer) error {
+	panic("not implemented")
+}
+`
+	if got := write.Code(); got != want {
+		T.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestSourceError(T *testing.T) {
+	for _, p := range []proto.ProtoType{
+		proto.Describe(func(*rand.Rand, struct{ io.Reader }) chan (<-chan int) { return nil }),
+		proto.Describe(struct{ io.Reader }{}),
+		&proto.Instance{T: proto.T{Proto: "nil"}},
+		nil,
+		// 泛型实例缺少类型参数的声明
+		proto.Describe(List[int]{}),
+		// 底层类型未注册
+		proto.Describe(IDs{}),
+		proto.Describe(1),
+		// 生成的代码不能通过 gofmt
+		&proto.Struct{T: proto.T{Name: "a b", Proto: "example.com/gen.a b"}},
+	} {
+		if _, err := proto.Source("example.com/gen", p); err == nil || !strings.HasPrefix(err.Error(), "proto code failed: ") {
+			T.Errorf("%v: bad error %v", p, err)
+		}
+	}
+}
+
+func TestNamedCode(T *testing.T) {
+	r := &proto.Registry{}
+	r.Register(IDs{}, Names{})
+	got, err := r.Source("github.com/gohub/typeless/proto_test", proto.Describe(IDs{}), proto.Describe(Names{}))
+	want := `package proto_test
+
+type IDs []int
+
+type Names map[string]IDs
+`
+	if got != want || err != nil {
+		T.Errorf("want:\n%s\ngot:\n%s %v", want, got, err)
+	}
+
+	// 给出变量名时生成变量声明
+	if got, want := proto.Describe(IDs{}).Code("ids"), "import \"github.com/gohub/typeless/proto_test\"\n\nvar ids proto_test.IDs\n"; got != want {
+		T.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestFnCode(T *testing.T) {
+	got := proto.Describe(func(chan (<-chan int), ...[]byte) {}).Code("Pipe")
+	want := "func Pipe(a0 chan (<-chan int), a1 ...[]uint8) {\n\tpanic(\"not implemented\")\n}\n"
+	if got != want {
+		T.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	// 命名的函数类型生成类型声明, 当前包是类型所在的包
+	got = proto.Describe(http.HandlerFunc(nil)).Code()
+	want = "type HandlerFunc func(ResponseWriter, *Request)\n"
+	if got != want {
+		T.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,134 @@
+package proto
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 返回 x 的原型描述, x 可以是值或者 reflect.Type.
+//
+//	func      返回 *Fn
+//	interface 返回 *Interface
+//	struct    返回 *Struct, 指向 struct 的指针也返回 *Struct
+//	其他      返回 *T
+//
+// Struct.Methods 包含指针接收者的方法.
+func Describe(x interface{}) ProtoType {
+	if x == nil {
+		return &T{Proto: "nil"}
+	}
+	t := TypeOf(x)
+	switch t.Kind() {
+	case reflect.Func:
+		fn := describeFunc(t, 0)
+		return &fn
+	case reflect.Interface:
+		return describeInterface(t)
+	case reflect.Ptr:
+		if t.Elem().Kind() == reflect.Struct {
+			return describeStruct(t.Elem())
+		}
+	case reflect.Struct:
+		return describeStruct(t)
+	}
+	return &T{Name: typeName(t), Proto: prototype(t)}
+}
+
+// 类型名称, 泛型实例的类型参数采用 proto 描述
+func typeName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i != -1 && t.PkgPath() != "" {
+		name = name[:i] + std.typeArgs(name[i:])
+	}
+	return name
+}
+
+// 变量, 字段, 参数的描述, 包含 Type
+func describeT(name string, t reflect.Type) T {
+	return T{Name: name, Type: t.String(), Proto: prototype(t)}
+}
+
+// skip 是跳过的参数个数, 用于剥去方法的接收者
+func describeFunc(t reflect.Type, skip int) (fn Fn) {
+	fn.In = make([]T, 0, t.NumIn()-skip)
+	fn.Out = make([]T, 0, t.NumOut())
+	in := make([]reflect.Type, 0, t.NumIn()-skip)
+	out := make([]reflect.Type, 0, t.NumOut())
+	for i := skip; i < t.NumIn(); i++ {
+		in = append(in, t.In(i))
+		fn.In = append(fn.In, describeT("", t.In(i)))
+	}
+	for i := 0; i < t.NumOut(); i++ {
+		out = append(out, t.Out(i))
+		fn.Out = append(fn.Out, describeT("", t.Out(i)))
+	}
+	fn.Variadic = t.IsVariadic()
+	if skip != 0 {
+		t = reflect.FuncOf(in, out, fn.Variadic)
+	}
+	fn.T = T{Name: typeName(t), Proto: prototype(t)}
+	return
+}
+
+func describeInterface(t reflect.Type) *Interface {
+	return &Interface{T: T{Name: typeName(t), Proto: prototype(t)}, Methods: methods(t)}
+}
+
+func describeStruct(t reflect.Type) *Struct {
+	s := &Struct{T: T{Name: typeName(t), Proto: prototype(t)}, Methods: methods(t)}
+	s.Fields = make([]Field, t.NumField())
+	for i := range s.Fields {
+		f := t.Field(i)
+		s.Fields[i] = Field{
+			T:        describeT(f.Name, f.Type),
+			Tag:      string(f.Tag),
+			Embedded: f.Anonymous,
+			Exported: f.PkgPath == "",
+		}
+	}
+	return s
+}
+
+// 返回 x 的方法集, 按名称排序. x 可以是值或者 reflect.Type,
+// 非指针类型也包含指针接收者的方法. Fn.Proto 不包含接收者.
+func Methods(x interface{}) []Fn {
+	if x == nil {
+		return nil
+	}
+	m := methods(TypeOf(x))
+	fns := make([]Fn, 0, len(m))
+	for _, name := range methodNames(m) {
+		fns = append(fns, m[name])
+	}
+	return fns
+}
+
+// 返回 t 的方法集, 非指针类型包含指针接收者的方法
+func methods(t reflect.Type) map[string]Fn {
+	m := map[string]Fn{}
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface {
+		t = reflect.PointerTo(t)
+	}
+	skip := 1
+	if t.Kind() == reflect.Interface {
+		skip = 0
+	}
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		fn := describeFunc(method.Type, skip)
+		fn.Name = method.Name
+		m[method.Name] = fn
+	}
+	return m
+}
+
+// 返回排序后的方法名
+func methodNames(m map[string]Fn) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
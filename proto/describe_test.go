@@ -0,0 +1,122 @@
+package proto_test
+
+import (
+	"fmt"
+	"github.com/gohub/typeless/proto"
+	"io"
+	"net/http"
+	"testing"
+)
+
+type Base struct {
+	ID int
+}
+
+func (b Base) Key() int { return b.ID }
+
+type User struct {
+	Base
+	Name  string `json:"name"`
+	Tags  []string
+	email string
+}
+
+func (u *User) SetName(name string, more ...string) error { return nil }
+
+func TestDescribeStruct(T *testing.T) {
+	s, ok := proto.Describe(&User{}).(*proto.Struct)
+	if !ok {
+		T.Fatalf("want *proto.Struct, but %T", proto.Describe(&User{}))
+	}
+	if s.Name != "User" || s.Proto != "github.com/gohub/typeless/proto_test.User" {
+		T.Errorf("bad struct %#v", s.T)
+	}
+	want := []proto.Field{
+		{T: proto.T{Name: "Base", Type: "proto_test.Base", Proto: "github.com/gohub/typeless/proto_test.Base"}, Embedded: true, Exported: true},
+		{T: proto.T{Name: "Name", Type: "string", Proto: "string"}, Tag: `json:"name"`, Exported: true},
+		{T: proto.T{Name: "Tags", Type: "[]string", Proto: "[]string"}, Exported: true},
+		{T: proto.T{Name: "email", Type: "string", Proto: "string"}},
+	}
+	if len(s.Fields) != len(want) {
+		T.Fatalf("want %d fields, but %d", len(want), len(s.Fields))
+	}
+	for i, f := range s.Fields {
+		if f != want[i] {
+			T.Errorf("want: %#v\n got: %#v", want[i], f)
+		}
+	}
+	if len(s.Methods) != 2 {
+		T.Fatalf("want 2 methods, but %v", s.Methods)
+	}
+	fn := s.Methods["SetName"]
+	if got := fn.String(); got != "func SetName(string, ...string) error" {
+		T.Errorf("bad method %s", got)
+	}
+	if fn := s.Methods["Key"]; fn.String() != "func Key() int" {
+		T.Errorf("bad method %s", fn.String())
+	}
+}
+
+func TestDescribeInterface(T *testing.T) {
+	i, ok := proto.Describe(proto.TypeIndirect((*io.ReadWriter)(nil))).(*proto.Interface)
+	if !ok {
+		T.Fatal("want *proto.Interface")
+	}
+	if i.Proto != "io.ReadWriter" || len(i.Methods) != 2 {
+		T.Errorf("bad interface %#v", i)
+	}
+	if fn := i.Methods["Read"]; fn.String() != "func Read([]uint8) (int, error)" {
+		T.Errorf("bad method %s", fn.String())
+	}
+}
+
+func TestDescribeFunc(T *testing.T) {
+	fn, ok := proto.Describe(func(w http.ResponseWriter, r *http.Request) {}).(*proto.Fn)
+	if !ok {
+		T.Fatal("want *proto.Fn")
+	}
+	if fn.String() != "func(net/http.ResponseWriter, *net/http.Request)" ||
+		len(fn.In) != 2 || len(fn.Out) != 0 || fn.In[1].Proto != "*net/http.Request" {
+		T.Errorf("bad func %#v", fn)
+	}
+	// 命名的函数类型
+	named := proto.Describe(http.HandlerFunc(nil))
+	if s := named.String(); s != "type HandlerFunc func(net/http.ResponseWriter, *net/http.Request)" {
+		T.Errorf("bad named func %s", s)
+	}
+	if s := proto.Describe(1).String(); s != "int" {
+		T.Errorf("want int, but %s", s)
+	}
+	if s := proto.Describe(nil).String(); s != "nil" {
+		T.Errorf("want nil, but %s", s)
+	}
+}
+
+func TestTNew(T *testing.T) {
+	p, ok := proto.Describe([]int{}).New().(*[]int)
+	if !ok || p == nil {
+		T.Errorf("want *[]int, but %#v", p)
+	}
+}
+
+func TestMethods(T *testing.T) {
+	for _, c := range []struct {
+		x    interface{}
+		want string
+	}{
+		{User{}, "[func Key() int func SetName(string, ...string) error]"},
+		{&User{}, "[func Key() int func SetName(string, ...string) error]"},
+		{Base{}, "[func Key() int]"},
+		{proto.TypeIndirect((*io.ReadCloser)(nil)), "[func Close() error func Read([]uint8) (int, error)]"},
+		{1, "[]"},
+	} {
+		fns := proto.Methods(c.x)
+		ss := make([]string, len(fns))
+		for i := range fns {
+			ss[i] = fns[i].String()
+		}
+		if got := fmt.Sprint(ss); got != c.want {
+			T.Errorf("want: %s\n got: %s", c.want, got)
+		}
+	}
+}
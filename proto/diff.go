@@ -0,0 +1,268 @@
+package proto
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// 一项 API 变更. Path 是变更的位置, 例如 User, User.Name, User.SetName.
+// Kind 是 added, removed, renamed 或 changed, Old, New 是变更前后的 proto 描述,
+// renamed 时是变更前后的名称.
+type Change struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+// 文本形式, 例如
+//
+//	breaking   User.Name: changed string -> int
+func (c Change) String() string {
+	s := "compatible "
+	if c.Breaking {
+		s = "breaking   "
+	}
+	s += c.Path + ": " + c.Kind
+	switch {
+	case c.Old != "" && c.New != "":
+		s += " " + c.Old + " -> " + c.New
+	case c.Old != "":
+		s += " " + c.Old
+	case c.New != "":
+		s += " " + c.New
+	}
+	return s
+}
+
+// 变更列表
+type Changes []Change
+
+// 是否包含不兼容的变更
+func (cs Changes) Breaking() bool {
+	for _, c := range cs {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// 文本报告, 每行一项变更
+func (cs Changes) String() string {
+	s := ""
+	for _, c := range cs {
+		s += c.String() + "\n"
+	}
+	return s
+}
+
+// JSON 报告, 没有变更时为 []
+func (cs Changes) JSON() ([]byte, error) {
+	if cs == nil {
+		cs = Changes{}
+	}
+	return json.Marshal(cs)
+}
+
+// 比较两个描述的 API 变更, 支持 *T, *Fn, *Interface, *Struct.
+// 规则如下
+//   - 种类或 proto 描述不同的类型是不兼容的变更
+//   - 删除, 改名, 改变类型的导出字段是不兼容的, 增加导出字段和改变 tag 是兼容的
+//   - 非导出字段的变更是兼容的
+//   - 删除或改变签名的方法是不兼容的, struct 增加方法是兼容的, interface 增加方法是不兼容的
+//   - 函数签名的任何变化都是不兼容的
+func Diff(old, new ProtoType) Changes {
+	var d differ
+	d.diff(pathOf(old), old, new)
+	return d.cs
+}
+
+// 比较两组描述, 例如两个版本的包, 以名称对应, 无名称的描述以 Proto 对应.
+// 删除的类型是不兼容的, 增加的类型是兼容的.
+func DiffAll(old, new []ProtoType) Changes {
+	var d differ
+	m := map[string]ProtoType{}
+	for _, p := range new {
+		m[pathOf(p)] = p
+	}
+	for _, p := range old {
+		key := pathOf(p)
+		if q, ok := m[key]; ok {
+			d.diff(key, p, q)
+			delete(m, key)
+		} else {
+			d.add(key, "removed", protoOf(p), "", true)
+		}
+	}
+	added := make([]string, 0, len(m))
+	for key := range m {
+		added = append(added, key)
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		d.add(key, "added", "", protoOf(m[key]), false)
+	}
+	return d.cs
+}
+
+func protoOf(p ProtoType) string {
+	switch p := p.(type) {
+	case *T:
+		return p.Proto
+	case *Fn:
+		return p.Proto
+	case *Interface:
+		return p.Proto
+	case *Struct:
+		return p.Proto
+	case *Instance:
+		return p.Proto
+	}
+	return ""
+}
+
+// 有名称时使用名称, 否则使用 proto 描述
+func pathOf(p ProtoType) string {
+	var t *T
+	switch p := p.(type) {
+	case *T:
+		t = p
+	case *Fn:
+		t = &p.T
+	case *Interface:
+		t = &p.T
+	case *Struct:
+		t = &p.T
+	case *Instance:
+		t = &p.T
+	default:
+		return ""
+	}
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Proto
+}
+
+type differ struct {
+	cs Changes
+}
+
+func (d *differ) add(path, kind, old, new string, breaking bool) {
+	d.cs = append(d.cs, Change{Path: path, Kind: kind, Old: old, New: new, Breaking: breaking})
+}
+
+func (d *differ) diff(path string, old, new ProtoType) {
+	switch o := old.(type) {
+	case *Struct:
+		if n, ok := new.(*Struct); ok {
+			d.structs(path, o, n)
+			return
+		}
+	case *Interface:
+		if n, ok := new.(*Interface); ok {
+			d.methods(path, o.Methods, n.Methods, true)
+			return
+		}
+	case *Fn:
+		if n, ok := new.(*Fn); ok {
+			d.fn(path, o, n)
+			return
+		}
+	case *T:
+		if n, ok := new.(*T); ok {
+			if o.Proto != n.Proto {
+				d.add(path, "changed", o.Proto, n.Proto, true)
+			}
+			return
+		}
+	}
+	d.add(path, "changed", protoOf(old), protoOf(new), true)
+}
+
+func (d *differ) fn(path string, old, new *Fn) {
+	o, n := old.signature(), new.signature()
+	if o != n {
+		d.add(path, "changed", "func"+o, "func"+n, true)
+	}
+}
+
+func (d *differ) structs(path string, old, new *Struct) {
+	fields := make(map[string]Field, len(new.Fields))
+	for _, f := range new.Fields {
+		fields[f.Name] = f
+	}
+	var removed []Field
+	for _, f := range old.Fields {
+		n, ok := fields[f.Name]
+		if !ok {
+			removed = append(removed, f)
+			continue
+		}
+		delete(fields, f.Name)
+		p := path + "." + f.Name
+		breaking := f.Exported || n.Exported
+		switch {
+		case f.Proto != n.Proto:
+			d.add(p, "changed", f.Proto, n.Proto, breaking)
+		case f.Embedded != n.Embedded:
+			d.add(p, "changed", f.String(), n.String(), breaking)
+		case f.Exported != n.Exported:
+			d.add(p, "changed", f.String(), n.String(), f.Exported)
+		case f.Tag != n.Tag:
+			d.add(p, "changed", f.String(), n.String(), false)
+		}
+	}
+	// 删除的字段与同一位置, 同一类型的新字段视为改名
+	for _, f := range removed {
+		p := path + "." + f.Name
+		if n, ok := renamed(f, old, new, fields); ok {
+			delete(fields, n.Name)
+			d.add(p, "renamed", f.Name, n.Name, f.Exported)
+		} else {
+			d.add(p, "removed", f.Proto, "", f.Exported)
+		}
+	}
+	for _, f := range new.Fields {
+		if _, ok := fields[f.Name]; ok {
+			d.add(path+"."+f.Name, "added", "", f.Proto, false)
+		}
+	}
+	d.methods(path, old.Methods, new.Methods, false)
+}
+
+func renamed(f Field, old, new *Struct, added map[string]Field) (Field, bool) {
+	for i := range old.Fields {
+		if old.Fields[i].Name != f.Name {
+			continue
+		}
+		if i < len(new.Fields) {
+			n := new.Fields[i]
+			if _, ok := added[n.Name]; ok && n.Proto == f.Proto {
+				return n, true
+			}
+		}
+	}
+	return Field{}, false
+}
+
+// iface 表示是接口, 接口增加方法也是不兼容的
+func (d *differ) methods(path string, old, new map[string]Fn, iface bool) {
+	for _, name := range methodNames(old) {
+		o := old[name]
+		p := path + "." + name
+		if n, ok := new[name]; !ok {
+			d.add(p, "removed", "func"+o.signature(), "", true)
+		} else if o.signature() != n.signature() {
+			d.add(p, "changed", "func"+o.signature(), "func"+n.signature(), true)
+		}
+	}
+	for _, name := range methodNames(new) {
+		if _, ok := old[name]; !ok {
+			n := new[name]
+			d.add(path+"."+name, "added", "", "func"+n.signature(), iface)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"io"
+	"testing"
+)
+
+type userV1 struct {
+	ID    int
+	Name  string `json:"name"`
+	Email string
+	note  string
+}
+
+type userV2 struct {
+	ID   int64
+	Name string `json:"full_name"`
+	Mail string
+	Age  int
+}
+
+func (u *userV2) Close() error { return nil }
+
+func TestDiffStruct(T *testing.T) {
+	cs := proto.Diff(proto.Describe(userV1{}), proto.Describe(userV2{}))
+	want := `breaking   userV1.ID: changed int -> int64
+compatible userV1.Name: changed Name string "json:\"name\"" -> Name string "json:\"full_name\""
+breaking   userV1.Email: renamed Email -> Mail
+compatible userV1.note: removed string
+compatible userV1.Age: added int
+compatible userV1.Close: added func() error
+`
+	if got := cs.String(); got != want {
+		T.Errorf("want: %s\n got: %s", want, got)
+	}
+	if !cs.Breaking() {
+		T.Error("want breaking")
+	}
+	b, err := cs[:1].JSON()
+	if err != nil {
+		T.Fatal(err)
+	}
+	if want := `[{"path":"userV1.ID","kind":"changed","old":"int","new":"int64","breaking":true}]`; string(b) != want {
+		T.Errorf("want: %s\n got: %s", want, b)
+	}
+	if b, _ := proto.Diff(proto.Describe(User{}), proto.Describe(User{})).JSON(); string(b) != "[]" {
+		T.Errorf("want: []\n got: %s", b)
+	}
+}
+
+func TestDiffInterfaceFunc(T *testing.T) {
+	for _, c := range []struct {
+		old, new interface{}
+		want     string
+	}{
+		{
+			proto.TypeIndirect((*io.Reader)(nil)), proto.TypeIndirect((*io.ReadCloser)(nil)),
+			"breaking   Reader.Close: added func() error\n",
+		},
+		{
+			proto.TypeIndirect((*io.ReadCloser)(nil)), proto.TypeIndirect((*io.Reader)(nil)),
+			"breaking   ReadCloser.Close: removed func() error\n",
+		},
+		{
+			func(int) error { return nil }, func(int, ...string) error { return nil },
+			"breaking   func(int) error: changed func(int) error -> func(int, ...string) error\n",
+		},
+		{
+			func(int) error { return nil }, 1,
+			"breaking   func(int) error: changed func(int) error -> int\n",
+		},
+		{1, 1, ""},
+	} {
+		if got := proto.Diff(proto.Describe(c.old), proto.Describe(c.new)).String(); got != c.want {
+			T.Errorf("want: %s\n got: %s", c.want, got)
+		}
+	}
+}
+
+func TestDiffAll(T *testing.T) {
+	old := []proto.ProtoType{proto.Describe(Base{}), proto.Describe(User{})}
+	new := []proto.ProtoType{proto.Describe(Base{}), proto.Describe(IDs{})}
+	want := "breaking   User: removed github.com/gohub/typeless/proto_test.User\n" +
+		"compatible IDs: added github.com/gohub/typeless/proto_test.IDs\n"
+	if got := proto.DiffAll(old, new).String(); got != want {
+		T.Errorf("want: %s\n got: %s", want, got)
+	}
+}
+
+func named(name string, v interface{}) proto.ProtoType {
+	p := proto.Describe(v).(*proto.Fn)
+	p.Name = name
+	return p
+}
+
+func TestDiffAllFuncs(T *testing.T) {
+	old := []proto.ProtoType{
+		named("A", func(int) error { return nil }),
+		named("B", func(int) error { return nil }),
+	}
+	if got := proto.DiffAll(old, old).String(); got != "" {
+		T.Errorf("want: \n got: %s", got)
+	}
+	new := []proto.ProtoType{
+		named("A", func(int) error { return nil }),
+		named("B", func(string) error { return nil }),
+	}
+	want := "breaking   B: changed func(int) error -> func(string) error\n"
+	if got := proto.DiffAll(old, new).String(); got != want {
+		T.Errorf("want: %s\n got: %s", want, got)
+	}
+}
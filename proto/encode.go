@@ -0,0 +1,369 @@
+package proto
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+func toEncodeFailed(s ...interface{}) error {
+	return errors.New("proto encode failed: " + fmt.Sprint(s...))
+}
+func toDecodeFailed(s ...interface{}) error {
+	return errors.New("proto decode failed: " + fmt.Sprint(s...))
+}
+
+// 序列化格式的版本, 解码时拒绝未知的版本
+const Version = 1
+
+// 序列化的描述种类, 下标是二进制格式中的编码
+var descKinds = []string{"", "type", "func", "interface", "struct", "instance"}
+
+func descKind(p ProtoType) (byte, error) {
+	switch p.(type) {
+	case *T:
+		return 1, nil
+	case *Fn:
+		return 2, nil
+	case *Interface:
+		return 3, nil
+	case *Struct:
+		return 4, nil
+	case *Instance:
+		return 5, nil
+	}
+	return 0, toEncodeFailed("unsupported ", fmt.Sprintf("%T", p))
+}
+
+func newDesc(kind byte) ProtoType {
+	switch kind {
+	case 1:
+		return &T{}
+	case 2:
+		return &Fn{}
+	case 3:
+		return &Interface{}
+	case 4:
+		return &Struct{}
+	}
+	return nil
+}
+
+// JSON 格式的外层结构
+type document struct {
+	Version int             `json:"version"`
+	Kind    string          `json:"kind"`
+	Desc    json.RawMessage `json:"desc"`
+}
+
+// 以 JSON 格式序列化描述, 支持 *T, *Fn, *Interface, *Struct, *Instance.
+// 格式为 {"version":1,"kind":"struct","desc":{...}}, 方法按名称排序, 结果是稳定的.
+// Instance.Value 使用 encoding/json 序列化.
+func EncodeJSON(p ProtoType) ([]byte, error) {
+	kind, err := descKind(p)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := json.Marshal(p)
+	if err != nil {
+		return nil, toEncodeFailed(err)
+	}
+	return json.Marshal(document{Version: Version, Kind: descKinds[kind], Desc: desc})
+}
+
+// 使用默认注册表解码 JSON 格式的描述
+func DecodeJSON(data []byte) (ProtoType, error) {
+	return Default.DecodeJSON(data)
+}
+
+// 解码 JSON 格式的描述. Instance.Value 的类型由注册表查找,
+// 未注册或者是接口类型时 Value 为 json.RawMessage.
+func (r *Registry) DecodeJSON(data []byte) (ProtoType, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, toDecodeFailed(err)
+	}
+	if doc.Version != Version {
+		return nil, toDecodeFailed("unsupported version ", doc.Version)
+	}
+	kind := byte(0)
+	for i, s := range descKinds {
+		if s != "" && s == doc.Kind {
+			kind = byte(i)
+		}
+	}
+	if kind == 0 {
+		return nil, toDecodeFailed("unknown kind ", doc.Kind)
+	}
+	if p := newDesc(kind); p != nil {
+		if err := json.Unmarshal(doc.Desc, p); err != nil {
+			return nil, toDecodeFailed(doc.Kind, ": ", err)
+		}
+		return p, nil
+	}
+	var x struct {
+		T
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(doc.Desc, &x); err != nil {
+		return nil, toDecodeFailed(doc.Kind, ": ", err)
+	}
+	v, err := r.value(x.Proto, x.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Instance{T: x.T, Value: v}, nil
+}
+
+// 由 JSON 解码 proto 类型的值
+func (r *Registry) value(proto string, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	t, err := r.Lookup(proto)
+	if err != nil || t.Kind() == reflect.Interface {
+		return raw, nil
+	}
+	v := reflect.New(t)
+	if err = json.Unmarshal(raw, v.Interface()); err != nil {
+		return nil, toDecodeFailed("value of ", proto, ": ", err)
+	}
+	return v.Elem().Interface(), nil
+}
+
+// 以紧凑的二进制格式序列化描述, 支持的描述与 EncodeJSON 相同.
+// 格式为版本, 种类各一个字节, 之后是描述的内容, 字符串和长度采用 uvarint 前缀.
+// Instance.Value 以 JSON 格式保存.
+func EncodeBinary(p ProtoType) ([]byte, error) {
+	kind, err := descKind(p)
+	if err != nil {
+		return nil, err
+	}
+	e := &encoder{b: []byte{Version, kind}}
+	switch p := p.(type) {
+	case *T:
+		e.t(p)
+	case *Fn:
+		e.fn(p)
+	case *Interface:
+		e.t(&p.T)
+		e.methods(p.Methods)
+	case *Struct:
+		e.t(&p.T)
+		e.uint(len(p.Fields))
+		for i := range p.Fields {
+			e.field(&p.Fields[i])
+		}
+		e.methods(p.Methods)
+	case *Instance:
+		e.t(&p.T)
+		b, err := json.Marshal(p.Value)
+		if err != nil {
+			return nil, toEncodeFailed(err)
+		}
+		e.string(string(b))
+	}
+	return e.b, nil
+}
+
+// 使用默认注册表解码二进制格式的描述
+func DecodeBinary(data []byte) (ProtoType, error) {
+	return Default.DecodeBinary(data)
+}
+
+// 解码二进制格式的描述, Instance.Value 的处理与 DecodeJSON 相同
+func (r *Registry) DecodeBinary(data []byte) (ProtoType, error) {
+	if len(data) < 2 {
+		return nil, toDecodeFailed("truncated data")
+	}
+	if data[0] != Version {
+		return nil, toDecodeFailed("unsupported version ", data[0])
+	}
+	kind := data[1]
+	if kind == 0 || int(kind) >= len(descKinds) {
+		return nil, toDecodeFailed("unknown kind ", kind)
+	}
+	d := &decoder{b: data[2:]}
+	var p ProtoType
+	switch kind {
+	case 1:
+		t := d.t()
+		p = &t
+	case 2:
+		fn := d.fn()
+		p = &fn
+	case 3:
+		p = &Interface{T: d.t(), Methods: d.methods()}
+	case 4:
+		s := &Struct{T: d.t()}
+		s.Fields = make([]Field, d.uint())
+		for i := range s.Fields {
+			s.Fields[i] = d.field()
+		}
+		s.Methods = d.methods()
+		p = s
+	case 5:
+		t, raw := d.t(), d.string()
+		if d.err != nil {
+			return nil, d.err
+		}
+		v, err := r.value(t.Proto, json.RawMessage(raw))
+		if err != nil {
+			return nil, err
+		}
+		p = &Instance{T: t, Value: v}
+	}
+	if d.err == nil && len(d.b) != 0 {
+		d.err = toDecodeFailed("unexpected trailing data")
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return p, nil
+}
+
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) uint(n int) {
+	e.b = binary.AppendUvarint(e.b, uint64(n))
+}
+
+func (e *encoder) string(s string) {
+	e.uint(len(s))
+	e.b = append(e.b, s...)
+}
+
+func (e *encoder) t(t *T) {
+	e.string(t.Name)
+	e.string(t.Type)
+	e.string(t.Proto)
+}
+
+func (e *encoder) fn(f *Fn) {
+	e.t(&f.T)
+	e.uint(len(f.In))
+	for i := range f.In {
+		e.t(&f.In[i])
+	}
+	e.uint(len(f.Out))
+	for i := range f.Out {
+		e.t(&f.Out[i])
+	}
+	if f.Variadic {
+		e.b = append(e.b, 1)
+	} else {
+		e.b = append(e.b, 0)
+	}
+}
+
+// 方法按名称排序
+func (e *encoder) methods(m map[string]Fn) {
+	e.uint(len(m))
+	for _, name := range methodNames(m) {
+		fn := m[name]
+		e.string(name)
+		e.fn(&fn)
+	}
+}
+
+// 字段标志位
+const (
+	fieldEmbedded = 1 << iota
+	fieldExported
+)
+
+func (e *encoder) field(f *Field) {
+	e.t(&f.T)
+	e.string(f.Tag)
+	flags := byte(0)
+	if f.Embedded {
+		flags |= fieldEmbedded
+	}
+	if f.Exported {
+		flags |= fieldExported
+	}
+	e.b = append(e.b, flags)
+}
+
+// 解码时遇到的第一个错误保存在 err 中, 之后的读取都返回零值
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) == 0 {
+		d.err = toDecodeFailed("truncated data")
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+// 长度不会超过剩余的数据, 防止错误的数据导致过大的内存分配
+func (d *decoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+	n, k := binary.Uvarint(d.b)
+	if k <= 0 || n > uint64(len(d.b)-k) {
+		d.err = toDecodeFailed("truncated data")
+		return 0
+	}
+	d.b = d.b[k:]
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.uint()
+	if d.err != nil {
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *decoder) t() T {
+	return T{Name: d.string(), Type: d.string(), Proto: d.string()}
+}
+
+func (d *decoder) fn() (f Fn) {
+	f.T = d.t()
+	f.In = make([]T, d.uint())
+	for i := range f.In {
+		f.In[i] = d.t()
+	}
+	f.Out = make([]T, d.uint())
+	for i := range f.Out {
+		f.Out[i] = d.t()
+	}
+	f.Variadic = d.byte() != 0
+	return
+}
+
+func (d *decoder) methods() map[string]Fn {
+	m := map[string]Fn{}
+	for n := d.uint(); n > 0 && d.err == nil; n-- {
+		name := d.string()
+		m[name] = d.fn()
+	}
+	return m
+}
+
+func (d *decoder) field() (f Field) {
+	f.T = d.t()
+	f.Tag = d.string()
+	flags := d.byte()
+	f.Embedded = flags&fieldEmbedded != 0
+	f.Exported = flags&fieldExported != 0
+	return
+}
@@ -0,0 +1,126 @@
+package proto_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gohub/typeless/proto"
+	"io"
+	"strings"
+	"testing"
+)
+
+func encodeCases() []proto.ProtoType {
+	return []proto.ProtoType{
+		proto.Describe(&User{}),
+		proto.Describe(proto.TypeIndirect((*io.ReadWriter)(nil))),
+		proto.Describe(func(string, ...int) (int, error) { return 0, nil }),
+		proto.Describe(List[map[string]*User]{}),
+		proto.Describe(1),
+		&proto.Instance{T: proto.T{Proto: "github.com/gohub/typeless/proto_test.Base"}, Value: Base{7}},
+		&proto.Instance{T: proto.T{Proto: "nil"}},
+	}
+}
+
+func TestEncode(T *testing.T) {
+	r := &proto.Registry{}
+	r.Register(Base{})
+	for _, p := range encodeCases() {
+		for _, c := range []struct {
+			encode func(proto.ProtoType) ([]byte, error)
+			decode func([]byte) (proto.ProtoType, error)
+		}{
+			{proto.EncodeJSON, r.DecodeJSON},
+			{proto.EncodeBinary, r.DecodeBinary},
+		} {
+			b, err := c.encode(p)
+			if err != nil {
+				T.Fatal(err)
+			}
+			got, err := c.decode(b)
+			if err != nil {
+				T.Fatalf("%s: %v", p, err)
+			}
+			if got.String() != p.String() {
+				T.Errorf("want: %s\n got: %s", p, got)
+			}
+			// 再次序列化的结果相同
+			again, err := c.encode(got)
+			if err != nil || !bytes.Equal(again, b) {
+				T.Errorf("want: %q\n got: %q %v", b, again, err)
+			}
+		}
+	}
+}
+
+func TestEncodeJSON(T *testing.T) {
+	b, err := proto.EncodeJSON(proto.Describe(func(string, ...int) error { return nil }))
+	if err != nil {
+		T.Fatal(err)
+	}
+	want := `{"version":1,"kind":"func","desc":{"proto":"func(string, ...int) error",` +
+		`"in":[{"type":"string","proto":"string"},{"type":"[]int","proto":"[]int"}],` +
+		`"out":[{"type":"error","proto":"error"}],"variadic":true}}`
+	if string(b) != want {
+		T.Errorf("want: %s\n got: %s", want, b)
+	}
+}
+
+func TestDecodeInstance(T *testing.T) {
+	r := &proto.Registry{}
+	r.Register(Base{})
+	b, err := proto.EncodeBinary(&proto.Instance{
+		T: proto.T{Proto: "github.com/gohub/typeless/proto_test.Base"}, Value: Base{7},
+	})
+	if err != nil {
+		T.Fatal(err)
+	}
+	p, err := r.DecodeBinary(b)
+	if err != nil {
+		T.Fatal(err)
+	}
+	if v, ok := p.(*proto.Instance).Value.(Base); !ok || v.ID != 7 {
+		T.Errorf("bad instance %#v", p)
+	}
+
+	// 未注册的类型保留 JSON
+	p, err = (&proto.Registry{}).DecodeBinary(b)
+	if err != nil {
+		T.Fatal(err)
+	}
+	if v, ok := p.(*proto.Instance).Value.(json.RawMessage); !ok || string(v) != `{"ID":7}` {
+		T.Errorf("bad instance %#v", p)
+	}
+
+	// 解码后可以生成实例
+	b, _ = proto.EncodeJSON(proto.Describe(Base{}))
+	p, err = r.DecodeJSON(b)
+	if err != nil {
+		T.Fatal(err)
+	}
+	v, err := r.Make(p, 3)
+	if b, ok := v.(*Base); !ok || b.ID != 3 {
+		T.Errorf("bad new %#v %v", v, err)
+	}
+}
+
+func TestDecodeError(T *testing.T) {
+	b, _ := proto.EncodeBinary(proto.Describe(&User{}))
+	for _, data := range [][]byte{
+		nil, {2, 1}, {1, 9}, b[:len(b)-1], append(b[:len(b):len(b)], 0), {1, 1, 0xff, 0xff, 0xff},
+	} {
+		if _, err := proto.DecodeBinary(data); err == nil || !strings.HasPrefix(err.Error(), "proto decode failed: ") {
+			T.Errorf("want an error for %v, got %v", data, err)
+		}
+	}
+	for _, data := range []string{
+		``, `{"version":2,"kind":"type","desc":{}}`, `{"version":1,"kind":"none","desc":{}}`,
+		`{"version":1,"kind":"func","desc":{"in":1}}`,
+	} {
+		if _, err := proto.DecodeJSON([]byte(data)); err == nil {
+			T.Errorf("want an error for %s", data)
+		}
+	}
+	if _, err := proto.EncodeJSON(nil); err == nil {
+		T.Error("want an error for nil")
+	}
+}
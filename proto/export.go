@@ -0,0 +1,204 @@
+package proto
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func toExportFailed(s ...interface{}) error {
+	return errors.New("proto export failed: " + fmt.Sprint(s...))
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// 导出器的类型解析. 参数是值或者 reflect.Type 时, 先把其中引用的命名类型收集到 local,
+// 查找时先查找 local, 再查找 Default.
+type resolver struct {
+	local *Registry
+}
+
+// 返回 x 的描述和解析器, x 可以是描述, 值或者 reflect.Type
+func newResolver(x interface{}) (ProtoType, *resolver) {
+	r := &resolver{local: &Registry{}}
+	if p, ok := x.(ProtoType); ok {
+		return p, r
+	}
+	t := TypeOf(x)
+	r.collect(t, map[reflect.Type]bool{})
+	p := Describe(t)
+	if s, ok := p.(*Struct); ok {
+		s.Fields = structFields(TypeIndirect(t))
+	}
+	return p, r
+}
+
+// struct 的字段, 匿名 struct 字段的 Proto 包含 tag, 见 StrictStruct
+func structFields(t reflect.Type) []Field {
+	fs := describeStruct(t).Fields
+	strict := &Printer{Mode: StrictStruct}
+	for i := range fs {
+		fs[i].Proto = strict.Type(t.Field(i).Type)
+	}
+	return fs
+}
+
+// 注册 t 中引用的全部命名类型
+func (r *resolver) collect(t reflect.Type, seen map[reflect.Type]bool) {
+	if t == nil || seen[t] {
+		return
+	}
+	seen[t] = true
+	if t.Name() != "" && t.PkgPath() != "" {
+		r.local.Register(t)
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Ptr, reflect.Chan:
+		r.collect(t.Elem(), seen)
+	case reflect.Map:
+		r.collect(t.Key(), seen)
+		r.collect(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			r.collect(t.Field(i).Type, seen)
+		}
+	}
+}
+
+func (r *resolver) lookup(s string) (reflect.Type, error) {
+	if t, err := r.local.Lookup(s); err == nil {
+		return t, nil
+	}
+	return Default.Lookup(s)
+}
+
+// 命名类型 t 的底层类型的 proto 描述, 只展开一层, 例如 type IDs []int 为 []int.
+// func, struct, interface 返回空字符串, 由调用者处理.
+func underlying(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Array:
+		return "[" + strconv.Itoa(t.Len()) + "]" + prototype(t.Elem())
+	case reflect.Slice:
+		return "[]" + prototype(t.Elem())
+	case reflect.Ptr:
+		return "*" + prototype(t.Elem())
+	case reflect.Map:
+		return "map[" + prototype(t.Key()) + "]" + prototype(t.Elem())
+	case reflect.Chan:
+		return t.ChanDir().String() + " " + prototype(t.Elem())
+	case reflect.Func, reflect.Struct, reflect.Interface:
+		return ""
+	}
+	return t.Kind().String()
+}
+
+// 字段在 JSON 中的名称和选项, skip 表示不出现在 JSON 中
+type jsonField struct {
+	name      string
+	omitempty bool
+	asString  bool
+	skip      bool
+	named     bool // tag 中给出了名称
+}
+
+// 按 encoding/json 的规则解析字段的 json tag
+func parseJSONField(f Field) (jf jsonField) {
+	tag, ok := reflect.StructTag(f.Tag).Lookup("json")
+	if tag == "-" {
+		jf.skip = true
+		return
+	}
+	if !f.Exported && !f.Embedded {
+		jf.skip = true
+		return
+	}
+	jf.name = f.Name
+	if !ok {
+		return
+	}
+	opts := strings.Split(tag, ",")
+	if opts[0] != "" {
+		jf.name, jf.named = opts[0], true
+	}
+	for _, o := range opts[1:] {
+		switch o {
+		case "omitempty":
+			jf.omitempty = true
+		case "string":
+			jf.asString = true
+		}
+	}
+	return
+}
+
+// 出现在 JSON 中的字段, path 是错误信息中的位置
+type jsonProp struct {
+	jsonField
+	Field
+	path string
+}
+
+// 按 encoding/json 的规则返回出现在 JSON 中的字段,
+// 未命名的嵌入 struct 展开到外层, 外层的同名字段优先.
+// 与 encoding/json 相同, 已经展开过的嵌入类型被跳过, 例如嵌入自身的 struct.
+func (r *resolver) jsonProps(path string, fields []Field) []jsonProp {
+	var props []jsonProp
+	r.appendJSONProps(&props, path, fields, map[string]bool{}, map[reflect.Type]bool{})
+	return props
+}
+
+func (r *resolver) appendJSONProps(props *[]jsonProp, path string, fields []Field, outer map[string]bool, visited map[reflect.Type]bool) {
+	var embedded []jsonProp
+	for _, f := range fields {
+		jf := parseJSONField(f)
+		if jf.skip {
+			continue
+		}
+		p := jsonProp{jf, f, path + "." + f.Name}
+		if f.Embedded && !jf.named {
+			embedded = append(embedded, p)
+			continue
+		}
+		if f.Exported && !outer[jf.name] {
+			*props = append(*props, p)
+			outer[jf.name] = true
+		}
+	}
+	for _, p := range embedded {
+		t, err := r.lookup(strings.TrimPrefix(p.Proto, "*"))
+		if err == nil && t.Kind() == reflect.Struct {
+			if !visited[t] {
+				visited[t] = true
+				r.appendJSONProps(props, p.path, structFields(t), outer, visited)
+			}
+			continue
+		}
+		// 非 struct 的嵌入字段以类型名作为字段名
+		if p.Exported && !outer[p.name] {
+			*props = append(*props, p)
+			outer[p.name] = true
+		}
+	}
+}
+
+// 匿名 struct 的字段转换为 Field, 未限定 PkgPath 时以首字母判断是否导出
+func exprFields(x *Expr) []Field {
+	fs := make([]Field, len(x.Fields))
+	for i, f := range x.Fields {
+		fs[i] = Field{
+			T:        T{Name: f.Name, Proto: f.Type.String()},
+			Tag:      f.Tag,
+			Embedded: f.Embedded,
+			Exported: f.PkgPath == "" && token.IsExported(f.Name),
+		}
+	}
+	return fs
+}
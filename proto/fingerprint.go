@@ -0,0 +1,96 @@
+package proto
+
+import (
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"reflect"
+	"sync"
+)
+
+// Hash 是 proto 描述的 128 位 FNV-1a 指纹. 只依赖默认配置的 proto 描述,
+// 不同进程和不同构建中相同类型的指纹相同, 可以用作缓存的键并持久化.
+type Hash [16]byte
+
+// 返回 x 的 proto 描述的指纹, x 可以是值或者 reflect.Type
+func Fingerprint(x interface{}) Hash {
+	if x == nil {
+		return FingerprintString("nil")
+	}
+	if _, ok := x.(error); ok {
+		return FingerprintString("error")
+	}
+	t := TypeOf(x)
+	if h, ok := hashCache.Load(t); ok {
+		return h.(Hash)
+	}
+	h := FingerprintString(prototype(t))
+	hashCache.Store(t, h)
+	return h
+}
+
+// 默认配置的指纹缓存, reflect.Type -> Hash
+var hashCache sync.Map
+
+// 返回 proto 描述 s 的指纹, 不检查 s 的语法
+func FingerprintString(s string) (h Hash) {
+	f := fnv.New128a()
+	f.Write([]byte(s))
+	f.Sum(h[:0])
+	return
+}
+
+// 64 位指纹, 取 Hash 的前 8 个字节
+func (h Hash) Uint64() uint64 {
+	var u uint64
+	for _, b := range h[:8] {
+		u = u<<8 | uint64(b)
+	}
+	return u
+}
+
+// 32 个字符的十六进制形式
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// 实现 encoding.TextMarshaler, Hash 可以作为 JSON 对象的键
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(b []byte) error {
+	if len(b) != hex.EncodedLen(len(h)) {
+		return errors.New("proto invalid fingerprint: " + string(b))
+	}
+	_, err := hex.Decode(h[:], b)
+	return err
+}
+
+// 解析 String 的结果
+func ParseHash(s string) (h Hash, err error) {
+	err = h.UnmarshalText([]byte(s))
+	return
+}
+
+// 在默认注册表中查找指纹对应的 reflect.Type
+func LookupFingerprint(h Hash) (reflect.Type, error) {
+	return Default.LookupFingerprint(h)
+}
+
+// 查找指纹对应的 reflect.Type. 只能找到内置类型, 注册过的类型,
+// 以及由 Lookup 生成过的复合类型.
+func (r *Registry) LookupFingerprint(h Hash) (reflect.Type, error) {
+	if s, ok := builtinHashes[h]; ok {
+		return builtins[s], nil
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if s, ok := r.hashes[h]; ok {
+		return r.m[s], nil
+	}
+	if t, ok := r.builtHashes[h]; ok {
+		return t, nil
+	}
+	return nil, toNotRegistered("fingerprint ", h)
+}
@@ -0,0 +1,80 @@
+package proto_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gohub/typeless/proto"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFingerprint(T *testing.T) {
+	// 指纹在不同进程和构建中保持不变
+	for _, c := range []struct {
+		x    interface{}
+		want string
+	}{
+		{0, "a68d3f92398b5822836dbc7969d97196"},
+		{nil, "a68d39f5948b5822836dbc7967539c6c"},
+		{&http.Request{}, "a0e49b20c447b50f32e6e98e9067a1ce"},
+		{func(http.ResponseWriter, *http.Request) {}, "4b4fe14775a88fdef69ed482df6e39f9"},
+	} {
+		h := proto.Fingerprint(c.x)
+		if h.String() != c.want {
+			T.Errorf("%s: want %s, got %s", proto.Type(c.x), c.want, h)
+		}
+		if h != proto.FingerprintString(proto.Type(c.x)) {
+			T.Errorf("%s: Fingerprint and FingerprintString differ", proto.Type(c.x))
+		}
+	}
+	if proto.Fingerprint(errors.New("x")) != proto.Fingerprint(reflect.TypeOf((*error)(nil)).Elem()) {
+		T.Error("error values must have the fingerprint of error")
+	}
+	if proto.Fingerprint(1).Uint64() == proto.Fingerprint("").Uint64() {
+		T.Error("want different fingerprints")
+	}
+}
+
+func TestFingerprintText(T *testing.T) {
+	m := map[proto.Hash]string{proto.Fingerprint(http.Cookie{}): "cookie"}
+	b, err := json.Marshal(m)
+	if err != nil {
+		T.Fatal(err)
+	}
+	var got map[proto.Hash]string
+	if err = json.Unmarshal(b, &got); err != nil {
+		T.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		T.Errorf("want %v, got %v", m, got)
+	}
+	for _, s := range []string{"", "xyz", proto.Fingerprint(0).String()[1:]} {
+		if _, err := proto.ParseHash(s); err == nil {
+			T.Errorf("want an error for %q", s)
+		}
+	}
+}
+
+func TestLookupFingerprint(T *testing.T) {
+	r := &proto.Registry{}
+	r.Register(http.Cookie{})
+	for _, x := range []interface{}{http.Cookie{}, "", proto.TypeIndirect((*error)(nil))} {
+		t, err := r.LookupFingerprint(proto.Fingerprint(x))
+		if err != nil {
+			T.Errorf("%s: %v", proto.Type(x), err)
+		} else if t != proto.TypeOf(x) {
+			T.Errorf("want %v, got %v", proto.TypeOf(x), t)
+		}
+	}
+	h := proto.Fingerprint([]*http.Cookie{})
+	if _, err := r.LookupFingerprint(h); err == nil {
+		T.Error("want an error before Lookup")
+	}
+	if _, err := r.Lookup(proto.Type([]*http.Cookie{})); err != nil {
+		T.Fatal(err)
+	}
+	if t, err := r.LookupFingerprint(h); err != nil || t != reflect.TypeOf([]*http.Cookie{}) {
+		T.Errorf("want []*http.Cookie, got %v, %v", t, err)
+	}
+}
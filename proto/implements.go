@@ -0,0 +1,197 @@
+package proto
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// 类型 Type 实现了接口 Interface, 二者都是 proto 描述.
+// Pointer 表示只有 *Type 实现了 Interface, 即用到了指针接收者的方法.
+type Impl struct {
+	Type      string `json:"type"`
+	Interface string `json:"interface"`
+	Pointer   bool   `json:"pointer,omitempty"`
+}
+
+// ImplementsIndex 是一组类型和接口之间的实现关系索引, 可以并发使用.
+// 零值是空的索引.
+type ImplementsIndex struct {
+	lock    sync.RWMutex
+	r       *Registry // 查找描述对应的 reflect.Type, nil 表示 Default
+	types   map[string]reflect.Type
+	ifaces  map[string]reflect.Type
+	methods map[string]map[string]Fn // 无法得到 reflect.Type 的接口描述的方法集
+	byType  map[string][]Impl
+	byIface map[string][]Impl
+}
+
+// 由类型和接口生成索引, 参数与 Add 相同
+func NewImplementsIndex(types ...interface{}) (*ImplementsIndex, error) {
+	x := &ImplementsIndex{}
+	return x, x.Add(types...)
+}
+
+// 由注册表中全部的类型和接口生成索引, 之后 Add 的描述也由 r 查找
+func (r *Registry) ImplementsIndex() (*ImplementsIndex, error) {
+	r.lock.RLock()
+	types := make([]interface{}, 0, len(r.m))
+	for _, t := range r.m {
+		types = append(types, t)
+	}
+	r.lock.RUnlock()
+	x := &ImplementsIndex{r: r}
+	return x, x.Add(types...)
+}
+
+// 待添加的接口, 没有 reflect.Type 时以方法集 m 比较
+type implIface struct {
+	s string
+	t reflect.Type
+	m map[string]Fn
+}
+
+// 添加类型或接口, 参数可以是值, reflect.Type 或者 Describe 的结果.
+// 接口需要以 reflect.Type 或者 *Interface 形式给出, 没有方法的接口被忽略.
+// 指针类型以其元素类型添加. 描述先在索引的注册表中查找 reflect.Type,
+// 找不到时 *Interface 以方法集比较, 其他描述返回错误, 此时不添加任何参数.
+func (x *ImplementsIndex) Add(types ...interface{}) error {
+	r := x.r
+	if r == nil {
+		r = Default
+	}
+	var ts []reflect.Type
+	var is []implIface
+	for _, a := range types {
+		if a == nil {
+			continue
+		}
+		var t reflect.Type
+		if p, ok := a.(ProtoType); ok {
+			s := protoOf(p)
+			var err error
+			if t, err = r.Lookup(s); err != nil {
+				i, ok := p.(*Interface)
+				if !ok {
+					return err
+				}
+				if len(i.Methods) != 0 {
+					is = append(is, implIface{s: s, m: i.Methods})
+				}
+				continue
+			}
+		} else {
+			t = TypeOf(a)
+		}
+		if t.Kind() == reflect.Interface {
+			if t.NumMethod() != 0 {
+				is = append(is, implIface{s: prototype(t), t: t})
+			}
+			continue
+		}
+		if t.Kind() == reflect.Ptr && t.Name() == "" {
+			t = t.Elem()
+		}
+		ts = append(ts, t)
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if x.types == nil {
+		x.types = map[string]reflect.Type{}
+		x.ifaces = map[string]reflect.Type{}
+		x.methods = map[string]map[string]Fn{}
+		x.byType = map[string][]Impl{}
+		x.byIface = map[string][]Impl{}
+	}
+	for _, t := range ts {
+		x.addType(t)
+	}
+	for _, i := range is {
+		x.addInterface(i.s, i.t, i.m)
+	}
+	return nil
+}
+
+func (x *ImplementsIndex) addType(t reflect.Type) {
+	s := prototype(t)
+	if _, ok := x.types[s]; ok {
+		return
+	}
+	x.types[s] = t
+	for is := range x.ifaces {
+		x.link(s, is)
+	}
+	for is := range x.methods {
+		x.link(s, is)
+	}
+}
+
+func (x *ImplementsIndex) addInterface(s string, t reflect.Type, m map[string]Fn) {
+	if _, ok := x.ifaces[s]; ok {
+		return
+	}
+	if _, ok := x.methods[s]; ok {
+		return
+	}
+	if t != nil {
+		x.ifaces[s] = t
+	} else {
+		x.methods[s] = m
+	}
+	for ts := range x.types {
+		x.link(ts, s)
+	}
+}
+
+// 判断并记录类型 ts 与接口 is 的实现关系
+func (x *ImplementsIndex) link(ts, is string) {
+	t := x.types[ts]
+	var ok, ptr bool
+	if it := x.ifaces[is]; it != nil {
+		ok = t.Implements(it)
+		if !ok && t.Kind() != reflect.Interface {
+			ok, ptr = reflect.PointerTo(t).Implements(it), true
+		}
+	} else {
+		m := x.methods[is]
+		ok = hasMethods(t, m)
+		if !ok {
+			ok, ptr = hasMethods(reflect.PointerTo(t), m), true
+		}
+	}
+	if ok {
+		impl := Impl{Type: ts, Interface: is, Pointer: ptr}
+		x.byType[ts] = append(x.byType[ts], impl)
+		x.byIface[is] = append(x.byIface[is], impl)
+	}
+}
+
+// 判断 t 自身的方法集是否包含 m 中全部的方法
+func hasMethods(t reflect.Type, m map[string]Fn) bool {
+	for name, fn := range m {
+		method, ok := t.MethodByName(name)
+		if !ok || describeFunc(method.Type, 1).Proto != fn.Proto {
+			return false
+		}
+	}
+	return true
+}
+
+// 返回类型 typ 实现的接口, 按接口排序. typ 是非指针类型的 proto 描述.
+func (x *ImplementsIndex) Implements(typ string) []Impl {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	impls := append([]Impl(nil), x.byType[typ]...)
+	sort.Slice(impls, func(i, j int) bool { return impls[i].Interface < impls[j].Interface })
+	return impls
+}
+
+// 返回实现接口 iface 的类型, 按类型排序
+func (x *ImplementsIndex) Implementers(iface string) []Impl {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+	impls := append([]Impl(nil), x.byIface[iface]...)
+	sort.Slice(impls, func(i, j int) bool { return impls[i].Type < impls[j].Type })
+	return impls
+}
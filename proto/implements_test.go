@@ -0,0 +1,94 @@
+package proto_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gohub/typeless/proto"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type keyer interface {
+	Key() int
+}
+
+// 不在 Default 中注册
+type unregistered struct{}
+
+func TestImplementsIndex(T *testing.T) {
+	x, err := proto.NewImplementsIndex(
+		Base{}, &User{}, bytes.Buffer{}, IDs{},
+		proto.TypeIndirect((*io.Reader)(nil)), proto.TypeIndirect((*fmt.Stringer)(nil)),
+		proto.TypeIndirect((*keyer)(nil)), proto.TypeIndirect((*interface{})(nil)),
+	)
+	if err != nil {
+		T.Fatal(err)
+	}
+	want := []proto.Impl{
+		{Type: "github.com/gohub/typeless/proto_test.User", Interface: "github.com/gohub/typeless/proto_test.keyer"},
+	}
+	if got := x.Implements("github.com/gohub/typeless/proto_test.User"); !reflect.DeepEqual(got, want) {
+		T.Errorf("want: %v\n got: %v", want, got)
+	}
+	want = []proto.Impl{
+		{Type: "bytes.Buffer", Interface: "fmt.Stringer", Pointer: true},
+		{Type: "bytes.Buffer", Interface: "io.Reader", Pointer: true},
+	}
+	if got := x.Implements("bytes.Buffer"); !reflect.DeepEqual(got, want) {
+		T.Errorf("want: %v\n got: %v", want, got)
+	}
+	want = []proto.Impl{
+		{Type: "github.com/gohub/typeless/proto_test.Base", Interface: "github.com/gohub/typeless/proto_test.keyer"},
+		{Type: "github.com/gohub/typeless/proto_test.User", Interface: "github.com/gohub/typeless/proto_test.keyer"},
+	}
+	if got := x.Implementers("github.com/gohub/typeless/proto_test.keyer"); !reflect.DeepEqual(got, want) {
+		T.Errorf("want: %v\n got: %v", want, got)
+	}
+	if got := x.Implementers("interface{}"); len(got) != 0 {
+		T.Errorf("empty interface must be ignored, got %v", got)
+	}
+}
+
+func TestImplementsIndexDescribe(T *testing.T) {
+	// 未注册的接口描述以方法集比较
+	setter := &proto.Interface{
+		T:       proto.T{Name: "Setter", Proto: "example.com/x.Setter"},
+		Methods: map[string]proto.Fn{"SetName": proto.Methods(&User{})[1]},
+	}
+	x, err := proto.NewImplementsIndex(setter, proto.Describe(unregistered{}))
+	if err == nil {
+		T.Fatal("want an error for unregistered struct")
+	}
+	if got := x.Implementers("example.com/x.Setter"); len(got) != 0 {
+		T.Errorf("failed Add must not change the index, got %v", got)
+	}
+	if err = x.Add(setter, &User{}, Base{}); err != nil {
+		T.Fatal(err)
+	}
+	want := []proto.Impl{
+		{Type: "github.com/gohub/typeless/proto_test.User", Interface: "example.com/x.Setter", Pointer: true},
+	}
+	if got := x.Implementers("example.com/x.Setter"); !reflect.DeepEqual(got, want) {
+		T.Errorf("want: %v\n got: %v", want, got)
+	}
+
+	r := &proto.Registry{}
+	r.Register(Base{}, proto.TypeIndirect((*keyer)(nil)))
+	want = []proto.Impl{
+		{Type: "github.com/gohub/typeless/proto_test.Base", Interface: "github.com/gohub/typeless/proto_test.keyer"},
+	}
+	x, err = r.ImplementsIndex()
+	if err != nil {
+		T.Fatal(err)
+	}
+	if got := x.Implements("github.com/gohub/typeless/proto_test.Base"); !reflect.DeepEqual(got, want) {
+		T.Errorf("want: %v\n got: %v", want, got)
+	}
+
+	// 描述由索引的注册表查找
+	r.Register(unregistered{})
+	if err = x.Add(proto.Describe(unregistered{})); err != nil {
+		T.Error(err)
+	}
+}
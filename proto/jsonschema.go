@@ -0,0 +1,233 @@
+package proto
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSON Schema 的版本
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// 生成 x 的 JSON Schema (draft 2020-12), x 可以是描述, 值或者 reflect.Type.
+// 规则如下
+//   - bool, 整数, 浮点数, string 对应 boolean, integer, number, string
+//   - []byte 对应 base64 编码的 string, 其他 slice 和 array 对应 array
+//   - map 对应 object, key 必须是 string 或整数
+//   - 与 encoding/json 一致, nil 的 slice 和 map 编码为 null, 因此它们也可以是 null
+//   - struct 按 encoding/json 的规则使用 json tag, omitempty 以外的字段是 required,
+//     未命名的嵌入 struct 字段展开到外层
+//   - 指针可以是 null, interface 可以是任意值
+//   - 命名类型放在 $defs 中, 键是 proto 描述, 递归引用通过 $ref 实现
+//   - time.Time 是 date-time 格式的 string, 实现了 encoding.TextMarshaler 的类型是 string,
+//     实现了 json.Marshaler 的类型可以是任意值
+//
+// 描述中引用的命名类型由 Default 查找, x 是值或 reflect.Type 时不需要注册.
+// complex, chan, func 等无法编码为 JSON 的类型返回错误.
+func JSONSchema(x interface{}) ([]byte, error) {
+	p, r := newResolver(x)
+	e := &schemaExporter{resolver: r, defs: map[string]interface{}{}}
+	root, err := e.root(p)
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = JSONSchemaDraft
+	if len(e.defs) != 0 {
+		root["$defs"] = e.defs
+	}
+	return json.MarshalIndent(root, "", "\t")
+}
+
+type schema = map[string]interface{}
+
+type schemaExporter struct {
+	*resolver
+	defs map[string]interface{} // proto 描述 -> schema
+}
+
+func (e *schemaExporter) root(p ProtoType) (schema, error) {
+	switch p := p.(type) {
+	case *Struct:
+		if p.Name == "" {
+			return e.object(p.Proto, p.Fields)
+		}
+		if _, ok := e.defs[p.Proto]; !ok {
+			e.defs[p.Proto] = nil
+			s, err := e.object(p.Name, p.Fields)
+			if err != nil {
+				return nil, err
+			}
+			e.defs[p.Proto] = s
+		}
+		return schema{"$ref": schemaRef(p.Proto)}, nil
+	case *T:
+		return e.typeOf(p.Proto, p.Proto)
+	case *Instance:
+		return e.typeOf(p.Proto, p.Proto)
+	}
+	return nil, toExportFailed("JSON Schema does not support ", protoOf(p))
+}
+
+// $defs 中 proto 描述的 JSON Pointer 引用
+func schemaRef(s string) string {
+	s = strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+	return "#" + (&url.URL{Fragment: "/$defs/" + s}).EscapedFragment()
+}
+
+// path 是错误信息中的位置, 例如 User.Tags
+func (e *schemaExporter) typeOf(path, s string) (schema, error) {
+	x, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return e.expr(path, x)
+}
+
+func (e *schemaExporter) expr(path string, x *Expr) (schema, error) {
+	switch x.Kind {
+	case KindNil:
+		return schema{"type": "null"}, nil
+	case KindIdent:
+		return e.ident(path, x)
+	case KindPtr:
+		elem, err := e.expr(path, x.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return schema{"anyOf": []interface{}{elem, schema{"type": "null"}}}, nil
+	case KindSlice, KindArray:
+		if x.Kind == KindSlice && x.Elem.Kind == KindIdent && x.Elem.PkgPath == "" && x.Elem.Name == "uint8" {
+			return schema{"type": []string{"string", "null"}, "contentEncoding": "base64"}, nil
+		}
+		items, err := e.expr(path+"[]", x.Elem)
+		if err != nil {
+			return nil, err
+		}
+		if x.Kind == KindArray {
+			return schema{"type": "array", "items": items, "minItems": x.Len, "maxItems": x.Len}, nil
+		}
+		return schema{"type": []string{"array", "null"}, "items": items}, nil
+	case KindMap:
+		if !e.isMapKey(x.Key) {
+			return nil, toExportFailed(path, ": unsupported map key ", x.Key)
+		}
+		elem, err := e.expr(path+"[]", x.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return schema{"type": []string{"object", "null"}, "additionalProperties": elem}, nil
+	case KindStruct:
+		return e.object(path, exprFields(x))
+	case KindInterface:
+		return schema{}, nil
+	}
+	return nil, toExportFailed(path, ": unsupported type ", x)
+}
+
+// 内置类型或者命名类型, 命名类型放入 $defs
+func (e *schemaExporter) ident(path string, x *Expr) (schema, error) {
+	if x.PkgPath == "" {
+		if s, ok := builtinSchema(x.Name); ok {
+			return s, nil
+		}
+		return nil, toExportFailed(path, ": unsupported type ", x)
+	}
+	s := x.String()
+	if _, ok := e.defs[s]; !ok {
+		t, err := e.lookup(s)
+		if err != nil {
+			return nil, toExportFailed(path, ": ", err)
+		}
+		e.defs[s] = nil // 递归时已经存在
+		def, err := e.named(path, t)
+		if err != nil {
+			return nil, err
+		}
+		e.defs[s] = def
+	}
+	return schema{"$ref": schemaRef(s)}, nil
+}
+
+func (e *schemaExporter) named(path string, t reflect.Type) (schema, error) {
+	pt := reflect.PointerTo(t)
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return schema{"type": "string", "format": "date-time"}, nil
+	case t.Implements(jsonMarshaler) || pt.Implements(jsonMarshaler):
+		return schema{}, nil
+	case t.Implements(textMarshaler) || pt.Implements(textMarshaler):
+		return schema{"type": "string"}, nil
+	case t.Kind() == reflect.Struct:
+		return e.object(typeName(t), structFields(t))
+	case t.Kind() == reflect.Interface:
+		return schema{}, nil
+	}
+	s := underlying(t)
+	if s == "" {
+		return nil, toExportFailed(path, ": unsupported type ", prototype(t))
+	}
+	return e.typeOf(path, s)
+}
+
+func builtinSchema(name string) (schema, bool) {
+	switch name {
+	case "bool":
+		return schema{"type": "boolean"}, true
+	case "string":
+		return schema{"type": "string"}, true
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+		return schema{"type": "integer"}, true
+	case "float32", "float64":
+		return schema{"type": "number"}, true
+	case "error":
+		return schema{}, true
+	}
+	return nil, false
+}
+
+// encoding/json 支持 string, 整数, 以及实现了 encoding.TextMarshaler 的 key
+func (e *schemaExporter) isMapKey(x *Expr) bool {
+	if x.Kind != KindIdent {
+		return false
+	}
+	if x.PkgPath == "" {
+		s, ok := builtinSchema(x.Name)
+		return ok && (s["type"] == "string" || s["type"] == "integer")
+	}
+	t, err := e.lookup(x.String())
+	if err != nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return t.Implements(textMarshaler)
+}
+
+// struct 对应的 object
+func (e *schemaExporter) object(path string, fields []Field) (schema, error) {
+	props := schema{}
+	var required []string
+	for _, p := range e.jsonProps(path, fields) {
+		var s schema
+		var err error
+		if p.asString {
+			s = schema{"type": "string"}
+		} else if s, err = e.typeOf(p.path, p.Proto); err != nil {
+			return nil, err
+		}
+		props[p.name] = s
+		if !p.omitempty {
+			required = append(required, p.name)
+		}
+	}
+	s := schema{"type": "object", "properties": props}
+	if len(required) != 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
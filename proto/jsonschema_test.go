@@ -0,0 +1,100 @@
+package proto_test
+
+import (
+	"encoding/json"
+	"github.com/gohub/typeless/proto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type Address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type Person struct {
+	Base
+	Name     string             `json:"name"`
+	Nick     string             `json:",omitempty"`
+	Secret   string             `json:"-"`
+	Age      int64              `json:"age,string"`
+	Home     *Address           `json:"home,omitempty"`
+	Addrs    []Address          `json:"addrs"`
+	Avatar   []byte             `json:"avatar"`
+	Scores   map[string]float64 `json:"scores"`
+	Pair     [2]bool            `json:"pair"`
+	Extra    interface{}        `json:"extra"`
+	Born     time.Time          `json:"born"`
+	Friends  []*Person          `json:"friends"`
+	internal int
+}
+
+// 嵌入自身的 struct, 与 encoding/json 相同只展开一次
+type Self struct {
+	*Self
+	X int `json:"x"`
+}
+
+func TestJSONSchema(T *testing.T) {
+	b, err := proto.JSONSchema(Person{})
+	if err != nil {
+		T.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "person.schema.json")
+	if *update {
+		if err = os.WriteFile(golden, b, 0644); err != nil {
+			T.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		T.Fatal(err)
+	}
+	if string(b) != string(want) {
+		T.Errorf("want:\n%s\n got:\n%s", want, b)
+	}
+	var v interface{}
+	if err = json.Unmarshal(b, &v); err != nil {
+		T.Error(err)
+	}
+}
+
+func TestJSONSchemaSelf(T *testing.T) {
+	b, err := proto.JSONSchema(Self{})
+	if err != nil {
+		T.Fatal(err)
+	}
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]interface{}
+		} `json:"$defs"`
+	}
+	if err = json.Unmarshal(b, &schema); err != nil {
+		T.Fatal(err)
+	}
+	props := schema.Defs["github.com/gohub/typeless/proto_test.Self"].Properties
+	if _, ok := props["x"]; !ok || len(props) != 1 {
+		T.Errorf("want property x only, got %s", b)
+	}
+}
+
+func TestJSONSchemaError(T *testing.T) {
+	for _, x := range []interface{}{
+		struct{ C chan int }{}, struct{ F func() }{}, struct{ M map[[2]int]string }{},
+		struct{ X complex128 }{}, func() {},
+	} {
+		if _, err := proto.JSONSchema(x); err == nil {
+			T.Errorf("want an error for %s", proto.Type(x))
+		}
+	}
+	// 未注册的命名类型
+	p := &proto.Struct{
+		T:      proto.T{Name: "S", Proto: "example.com/x.S"},
+		Fields: []proto.Field{{T: proto.T{Name: "A", Proto: "example.com/x.A"}, Exported: true}},
+	}
+	if _, err := proto.JSONSchema(p); err == nil {
+		T.Error("want an error for unregistered type")
+	}
+}
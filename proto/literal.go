@@ -0,0 +1,340 @@
+package proto
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 返回 v 的 Go 复合字面量写法, 类型名与 Type 一致, 例如
+//
+//	&example.com/x.User{Name: "a", Tags: []string{"b"}}
+//
+// v 可以是 reflect.Value. 规则如下
+//   - struct 以字段名写出非零值字段, 包括非导出字段
+//   - map 按 key 的值排序, 与 fmt 一致, 数值按大小, 字符串按字典序
+//   - 非 nil 的 func 写为 nil /* func */, chan 写为 make(chan T, cap)
+//   - 指向非复合类型的指针写为 func() *T { v := x; return &v }()
+//   - 循环引用写为 nil /* cycle */
+//
+// 上下文不能确定类型时, 非默认类型的值添加类型转换, 例如 uint8(1).
+func Literal(v interface{}) string {
+	return std.Literal(v)
+}
+
+// 返回 v 的 Go 字面量写法, 类型名由 p 生成
+func (p *Printer) Literal(v interface{}) string {
+	l := &literal{p: p, seen: map[visit]bool{}}
+	l.write(ValueOf(v), true)
+	return l.b.String()
+}
+
+// 上下文类型已知时的写法, 例如 ValueDiff 的结果
+func valueLiteral(v reflect.Value) string {
+	l := &literal{p: std, seen: map[visit]bool{}}
+	l.write(v, false)
+	return l.b.String()
+}
+
+// 返回 v 的 Go 代码写法, 必要时添加 import. 包含其他包非导出字段的值无法编译.
+func (im *Imports) Literal(v interface{}) string {
+	return (&Printer{Qualifier: im.Qualify}).Literal(v)
+}
+
+// 正在写出的引用, 用于检测循环
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type literal struct {
+	p    *Printer
+	b    strings.Builder
+	seen map[visit]bool
+}
+
+func (l *literal) typeName(t reflect.Type) string {
+	return l.p.typeOf(t)
+}
+
+// 类型转换, *T, func, <-chan 需要括号
+func (l *literal) convert(t reflect.Type, s string) {
+	name := l.typeName(t)
+	if strings.HasPrefix(name, "*") || strings.HasPrefix(name, "func") || strings.HasPrefix(name, "<-") {
+		name = "(" + name + ")"
+	}
+	l.b.WriteString(name + "(" + s + ")")
+}
+
+// typed 表示上下文不能确定类型, 比如顶层或者 interface 中的值
+func (l *literal) write(v reflect.Value, typed bool) {
+	if !v.IsValid() {
+		l.b.WriteString("nil")
+		return
+	}
+	t := v.Type()
+	switch k := t.Kind(); k {
+	case reflect.Interface:
+		if v.IsNil() {
+			l.b.WriteString("nil")
+			return
+		}
+		l.write(v.Elem(), true)
+	case reflect.Bool:
+		l.basic(v, typed, strconv.FormatBool(v.Bool()))
+	case reflect.String:
+		l.basic(v, typed, strconv.Quote(v.String()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		l.basic(v, typed, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		l.basic(v, typed, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		l.basic(v, typed, l.float(v.Float(), t.Bits()))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		bits := t.Bits() / 2
+		l.basic(v, typed, "complex("+l.float(real(c), bits)+", "+l.float(imag(c), bits)+")")
+	case reflect.UnsafePointer:
+		if v.IsNil() {
+			l.nil(t, typed)
+		} else {
+			l.b.WriteString("nil /* unsafe.Pointer */")
+		}
+	case reflect.Func:
+		if v.IsNil() {
+			l.nil(t, typed)
+		} else {
+			l.b.WriteString("nil /* func */")
+		}
+	case reflect.Chan:
+		if v.IsNil() {
+			l.nil(t, typed)
+		} else {
+			l.b.WriteString("make(" + l.typeName(t) + ", " + strconv.Itoa(v.Cap()) + ")")
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			l.nil(t, typed)
+			return
+		}
+		if !l.enter(v) {
+			return
+		}
+		defer l.leave(v)
+		switch t.Elem().Kind() {
+		case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+			l.b.WriteByte('&')
+			l.write(v.Elem(), true)
+		default:
+			l.b.WriteString("func() " + l.typeName(t) + " { v := ")
+			l.write(v.Elem(), true)
+			l.b.WriteString("; return &v }()")
+		}
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			l.nil(t, typed)
+			return
+		}
+		if !l.enter(v) {
+			return
+		}
+		defer l.leave(v)
+		if k == reflect.Map {
+			l.mapOf(v)
+		} else {
+			l.list(v)
+		}
+	case reflect.Array:
+		l.list(v)
+	case reflect.Struct:
+		l.b.WriteString(l.typeName(t))
+		l.b.WriteByte('{')
+		n := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			if f.IsZero() {
+				continue
+			}
+			if n != 0 {
+				l.b.WriteString(", ")
+			}
+			n++
+			l.b.WriteString(t.Field(i).Name)
+			l.b.WriteString(": ")
+			l.write(f, false)
+		}
+		l.b.WriteByte('}')
+	default:
+		l.b.WriteString("nil /* " + l.typeName(t) + " */")
+	}
+}
+
+// 基本类型的值, 类型为默认类型 bool, string, int, float64, complex128 时不需要转换
+func (l *literal) basic(v reflect.Value, typed bool, s string) {
+	t := v.Type()
+	if !typed || t.PkgPath() == "" && t.Name() != "" && isDefaultType(t.Kind()) {
+		l.b.WriteString(s)
+		return
+	}
+	l.convert(t, s)
+}
+
+func isDefaultType(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String, reflect.Int, reflect.Float64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// 浮点数总是包含 '.' 或者指数, 以免被当作整数常量
+func (l *literal) float(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return l.p.qualify("math", "NaN") + "()"
+	case math.IsInf(f, 1):
+		return l.p.qualify("math", "Inf") + "(1)"
+	case math.IsInf(f, -1):
+		return l.p.qualify("math", "Inf") + "(-1)"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if strings.IndexAny(s, ".e") == -1 {
+		s += ".0"
+	}
+	return s
+}
+
+func (l *literal) nil(t reflect.Type, typed bool) {
+	if typed {
+		l.convert(t, "nil")
+	} else {
+		l.b.WriteString("nil")
+	}
+}
+
+// 进入引用, 发现循环时写出占位并返回 false
+func (l *literal) enter(v reflect.Value) bool {
+	key := visit{v.Pointer(), v.Type()}
+	if l.seen[key] {
+		l.b.WriteString("nil /* cycle */")
+		return false
+	}
+	l.seen[key] = true
+	return true
+}
+
+func (l *literal) leave(v reflect.Value) {
+	delete(l.seen, visit{v.Pointer(), v.Type()})
+}
+
+// slice 或 array
+func (l *literal) list(v reflect.Value) {
+	l.b.WriteString(l.typeName(v.Type()))
+	l.b.WriteByte('{')
+	for i := 0; i < v.Len(); i++ {
+		if i != 0 {
+			l.b.WriteString(", ")
+		}
+		l.write(v.Index(i), false)
+	}
+	l.b.WriteByte('}')
+}
+
+func (l *literal) mapOf(v reflect.Value) {
+	t := v.Type()
+	l.b.WriteString(l.typeName(t))
+	l.b.WriteByte('{')
+	keys := sortMapKeys(v.MapKeys())
+	for i, k := range keys {
+		if i != 0 {
+			l.b.WriteString(", ")
+		}
+		l.b.WriteString(l.sub(k) + ": " + l.sub(v.MapIndex(k)))
+	}
+	l.b.WriteByte('}')
+}
+
+// 按值排序 map 的 key, 规则同 fmt 的 internal/fmtsort
+func sortMapKeys(keys []reflect.Value) []reflect.Value {
+	sort.SliceStable(keys, func(i, j int) bool { return compareValue(keys[i], keys[j]) < 0 })
+	return keys
+}
+
+// 比较同类型的两个值, 可以作为 map key 的类型才有意义.
+// NaN 小于其他浮点数, false 小于 true, nil 小于非 nil, 不同动态类型的 interface 按类型的写法比较.
+func compareValue(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, y := a.Int(), b.Int()
+		return order(x < y, x > y)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, y := a.Uint(), b.Uint()
+		return order(x < y, x > y)
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Float32, reflect.Float64:
+		return compareFloat(a.Float(), b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		x, y := a.Complex(), b.Complex()
+		if c := compareFloat(real(x), real(y)); c != 0 {
+			return c
+		}
+		return compareFloat(imag(x), imag(y))
+	case reflect.Bool:
+		return order(!a.Bool() && b.Bool(), a.Bool() && !b.Bool())
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Chan:
+		x, y := a.Pointer(), b.Pointer()
+		return order(x < y, x > y)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if c := compareValue(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if c := compareValue(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return order(a.IsNil() && !b.IsNil(), !a.IsNil() && b.IsNil())
+		}
+		x, y := a.Elem(), b.Elem()
+		if x.Type() != y.Type() {
+			return strings.Compare(Type(x.Type()), Type(y.Type()))
+		}
+		return compareValue(x, y)
+	}
+	return 0
+}
+
+// 比较的结果, less 和 greater 都不成立时相等
+func order(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return order(!math.IsNaN(b), !math.IsNaN(a))
+	}
+	return order(a < b, a > b)
+}
+
+// 单独写出 v, 与当前写出的引用共享循环检测
+func (l *literal) sub(v reflect.Value) string {
+	s := &literal{p: l.p, seen: l.seen}
+	s.write(v, false)
+	return s.b.String()
+}
@@ -0,0 +1,101 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Val  int
+	Next *node
+}
+
+func TestLiteral(T *testing.T) {
+	n := 1
+	loop := &node{Val: 1}
+	loop.Next = loop
+	for _, c := range []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "nil"},
+		{1, "1"},
+		{uint8(1), "uint8(1)"},
+		{1.0, "1.0"},
+		{float32(0.5), "float32(0.5)"},
+		{math.Inf(-1), "math.Inf(-1)"},
+		{complex64(1i), "complex64(complex(0.0, 1.0))"},
+		{"a\n", `"a\n"`},
+		{IDs{1, 2}, "github.com/gohub/typeless/proto_test.IDs{1, 2}"},
+		{IDs(nil), "github.com/gohub/typeless/proto_test.IDs(nil)"},
+		{Duration(3), "github.com/gohub/typeless/proto_test.Duration(3)"},
+		{&User{Name: "a", Tags: []string{"b"}},
+			`&github.com/gohub/typeless/proto_test.User{Name: "a", Tags: []string{"b"}}`},
+		{User{Base: Base{ID: 1}, email: "e"},
+			`github.com/gohub/typeless/proto_test.User{Base: github.com/gohub/typeless/proto_test.Base{ID: 1}, email: "e"}`},
+		{map[string]interface{}{"b": uint(2), "a": nil, "c": []int(nil)},
+			`map[string]interface{}{"a": nil, "b": uint(2), "c": []int(nil)}`},
+		{map[int]string{10: "c", 2: "b", 1: "a"}, `map[int]string{1: "a", 2: "b", 10: "c"}`},
+		{map[float64]bool{-1: true, 0.5: false, 10: true}, "map[float64]bool{-1.0: true, 0.5: false, 10.0: true}"},
+		{map[interface{}]int{"a": 1, 2: 2, 10: 3, nil: 0},
+			`map[interface{}]int{nil: 0, 2: 2, 10: 3, "a": 1}`},
+		{[2]*int{&n}, "[2]*int{func() *int { v := 1; return &v }(), nil}"},
+		{(*http.Request)(nil), "(*net/http.Request)(nil)"},
+		{struct{ F func() }{func() {}}, "struct { F func() }{F: nil /* func */}"},
+		{make(chan<- int, 2), "make(chan<- int, 2)"},
+		{loop, "&github.com/gohub/typeless/proto_test.node{Val: 1, Next: nil /* cycle */}"},
+		{[]*node{loop, loop}, "[]*github.com/gohub/typeless/proto_test.node{" +
+			"&github.com/gohub/typeless/proto_test.node{Val: 1, Next: nil /* cycle */}, " +
+			"&github.com/gohub/typeless/proto_test.node{Val: 1, Next: nil /* cycle */}}"},
+	} {
+		if got := proto.Literal(c.v); got != c.want {
+			T.Errorf("want: %s\n got: %s", c.want, got)
+		}
+	}
+}
+
+func TestImportsLiteral(T *testing.T) {
+	im := proto.NewImports("example.com/m")
+	v := map[string]*http.Cookie{"k": {Name: "n", MaxAge: 1}, "nan": {Raw: "x"}}
+	lit := im.Literal(v)
+	want := `map[string]*http.Cookie{"k": &http.Cookie{Name: "n", MaxAge: 1}, "nan": &http.Cookie{Raw: "x"}}`
+	if lit != want {
+		T.Errorf("want: %s\n got: %s", want, lit)
+	}
+	n := 1
+	lits := []string{
+		lit, im.Literal(math.NaN()), im.Literal(&n), im.Literal([]interface{}{1, "a", nil}),
+		im.Literal(struct {
+			D time.Duration
+			C chan int
+		}{time.Second, make(chan int, 2)}),
+	}
+	src := "package m\n\n" + im.Code() + "var _, _, _, _, _ = " + strings.Join(lits, ", ") + "\n"
+	if !strings.Contains(src, `"math"`) {
+		T.Errorf("want import math:\n%s", src)
+	}
+	if _, err := format.Source([]byte(src)); err != nil {
+		T.Errorf("%v\n%s", err, src)
+	}
+
+	// 生成的代码可以通过类型检查
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "m.go", src, 0)
+	if err != nil {
+		T.Fatalf("%v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err = conf.Check("example.com/m", fset, []*ast.File{f}, nil); err != nil {
+		T.Errorf("%v\n%s", err, src)
+	}
+}
@@ -0,0 +1,165 @@
+package proto
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+const (
+	anyType = "_"   // 任意一个类型
+	anyRest = "..." // 任意数量的剩余参数或返回值
+)
+
+func isRest(x *Expr) bool {
+	return x.Kind == KindIdent && x.PkgPath == "" && x.Name == anyRest
+}
+
+// Pattern 是编译后的 proto 模式, 语法与 proto 描述相同, 另外
+//   - _ 匹配任意一个类型, 例如 []_, map[string]_, *_, ..._
+//   - 参数或返回值列表最后的 ... 匹配任意数量的剩余类型, 包括可变参数,
+//     例如 func(*net/http.Request, ...) (_, error), func(...) (...)
+//
+// 匹配的对象是 Type 的结果, 即默认配置的 proto 描述.
+type Pattern struct {
+	s string
+	x *Expr
+}
+
+// 编译 proto 模式
+func Compile(pattern string) (*Pattern, error) {
+	p := &parser{s: pattern, pattern: true}
+	x, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(pattern) {
+		return nil, p.fail("unexpected ", strconv.Quote(pattern[p.pos:]))
+	}
+	return &Pattern{pattern, x}, nil
+}
+
+// 同 Compile, 失败时抛出 panic
+func MustCompile(pattern string) *Pattern {
+	p, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// 判断 x 的 proto 描述是否匹配 pattern, x 可以是值或者 reflect.Type
+func Match(pattern string, x interface{}) (bool, error) {
+	p, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return p.Match(x), nil
+}
+
+func (p *Pattern) String() string {
+	return p.s
+}
+
+// 判断 x 的 proto 描述是否匹配, x 可以是值或者 reflect.Type
+func (p *Pattern) Match(x interface{}) bool {
+	if x == nil {
+		return match(p.x, &Expr{Kind: KindNil})
+	}
+	if _, ok := x.(error); ok {
+		return match(p.x, &Expr{Kind: KindIdent, Name: "error"})
+	}
+	return match(p.x, exprOf(TypeOf(x)))
+}
+
+// 判断 proto 描述 s 是否匹配, s 无法解析时返回 false
+func (p *Pattern) MatchString(s string) bool {
+	x, err := Parse(s)
+	return err == nil && match(p.x, x)
+}
+
+// 默认配置的语法树缓存, reflect.Type -> *Expr
+var exprCache sync.Map
+
+func exprOf(t reflect.Type) *Expr {
+	if x, ok := exprCache.Load(t); ok {
+		return x.(*Expr)
+	}
+	x := MustParse(prototype(t))
+	exprCache.Store(t, x)
+	return x
+}
+
+func match(p, x *Expr) bool {
+	if p.Kind == KindIdent && p.PkgPath == "" && p.Name == anyType {
+		return true
+	}
+	if p.Kind != x.Kind {
+		return false
+	}
+	switch p.Kind {
+	case KindIdent:
+		return p.PkgPath == x.PkgPath && p.Name == x.Name && matchList(p.Args, x.Args)
+	case KindArray:
+		return p.Len == x.Len && match(p.Elem, x.Elem)
+	case KindSlice, KindPtr:
+		return match(p.Elem, x.Elem)
+	case KindMap:
+		return match(p.Key, x.Key) && match(p.Elem, x.Elem)
+	case KindChan:
+		return p.Dir == x.Dir && match(p.Elem, x.Elem)
+	case KindFunc:
+		if len(p.In) != 0 && isRest(p.In[len(p.In)-1]) {
+			n := len(p.In) - 1
+			// 可变参数只能整体被 ... 匹配
+			if len(x.In) < n || x.Variadic && len(x.In) == n {
+				return false
+			}
+			if !matchList(p.In[:n], x.In[:n]) {
+				return false
+			}
+		} else if p.Variadic != x.Variadic || !matchList(p.In, x.In) {
+			return false
+		}
+		return matchList(p.Out, x.Out)
+	case KindStruct:
+		return matchFields(p.Fields, x.Fields)
+	case KindInterface:
+		return matchFields(p.Methods, x.Methods)
+	}
+	return true
+}
+
+// 逐个匹配, p 的最后一个是 ... 时匹配剩余的全部
+func matchList(p, x []*Expr) bool {
+	if len(p) != 0 && isRest(p[len(p)-1]) {
+		p = p[:len(p)-1]
+		if len(x) < len(p) {
+			return false
+		}
+		x = x[:len(p)]
+	}
+	if len(p) != len(x) {
+		return false
+	}
+	for i := range p {
+		if !match(p[i], x[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchFields(p, x []ExprField) bool {
+	if len(p) != len(x) {
+		return false
+	}
+	for i := range p {
+		if p[i].PkgPath != x[i].PkgPath || p[i].Name != x[i].Name ||
+			p[i].Tag != x[i].Tag || p[i].Embedded != x[i].Embedded ||
+			!match(p[i].Type, x[i].Type) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package proto_test
+
+import (
+	"errors"
+	"github.com/gohub/typeless/proto"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMatch(T *testing.T) {
+	handler := func(http.ResponseWriter, *http.Request) {}
+	parse := func(*http.Request, string, ...int) (map[string]int, error) { return nil, nil }
+	for _, c := range []struct {
+		pattern string
+		x       interface{}
+		want    bool
+	}{
+		{"_", 1, true},
+		{"_", nil, true},
+		{"nil", nil, true},
+		{"error", errors.New("x"), true},
+		{"[]_", []string{}, true},
+		{"[]_", [1]string{}, false},
+		{"[2]_", [2]int{}, true},
+		{"map[string]_", map[string][]int{}, true},
+		{"map[string]_", map[int]string{}, false},
+		{"*_", &http.Request{}, true},
+		{"*net/http.Request", reflect.TypeOf(&http.Request{}), true},
+		{"chan<- _", make(chan<- int), true},
+		{"chan<- _", make(chan int), false},
+		{"func(...)", handler, true},
+		{"func(...)", parse, false},
+		{"func(_, ...)", handler, true},
+		{"func(_, _, _)", handler, false},
+		{"func(net/http.ResponseWriter, *_)", handler, true},
+		{"func(*net/http.Request, ...) (_, error)", parse, true},
+		{"func(*net/http.Request, ...) (...)", parse, true},
+		{"func(*net/http.Request, string, ...)", reflect.TypeOf(parse), false},
+		{"func(*net/http.Request, string, ..._) (map[_]_, _)", parse, true},
+		{"func(*net/http.Request, string, _) (...)", parse, false},
+		{"func(*net/http.Request, string, ...) (...)", parse, true},
+		{"func(*net/http.Request, string, int, ...) (...)", parse, false},
+		{"struct { A _; B []_ }", struct {
+			A int
+			B []string
+		}{}, true},
+		{"struct { A _ }", struct{ B int }{}, false},
+		{"interface { Close() error }", reflect.TypeOf((*interface{ Close() error })(nil)).Elem(), true},
+	} {
+		p, err := proto.Compile(c.pattern)
+		if err != nil {
+			T.Errorf("%s: %v", c.pattern, err)
+			continue
+		}
+		if got := p.Match(c.x); got != c.want {
+			T.Errorf("%s match %s: want %v, got %v", c.pattern, proto.Type(c.x), c.want, got)
+		}
+		if p.String() != c.pattern {
+			T.Errorf("want %s, got %s", c.pattern, p.String())
+		}
+	}
+}
+
+func TestMatchString(T *testing.T) {
+	p := proto.MustCompile("func(example.com/x.List[_], ...) _")
+	if !p.MatchString("func(example.com/x.List[int], string) bool") {
+		T.Error("want match")
+	}
+	if p.MatchString("func(example.com/x.List[int]) (bool, error)") {
+		T.Error("want mismatch")
+	}
+	if p.MatchString("func(") {
+		T.Error("invalid proto must not match")
+	}
+	if ok, err := proto.Match("[]_", []int{}); !ok || err != nil {
+		T.Errorf("want true, got %v, %v", ok, err)
+	}
+}
+
+func TestCompileError(T *testing.T) {
+	for _, s := range []string{
+		"", "...", "[]...", "func(..., int)", "func(int, ...) (..., error)", "func() ()", "func() ...", "map[...]int",
+	} {
+		if _, err := proto.Compile(s); err == nil {
+			T.Errorf("want an error for %q", s)
+		}
+	}
+	// ... 只在模式中有效
+	if _, err := proto.Parse("func(int, ...)"); err == nil {
+		T.Error("want an error for Parse")
+	}
+}
@@ -0,0 +1,235 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+func toNewFailed(s ...interface{}) error {
+	return errors.New("proto new failed: " + fmt.Sprint(s...))
+}
+
+// 生成 Proto 对应类型的零值, 返回指向零值的指针.
+// 类型由默认注册表 Default 查找, 找不到时返回 nil.
+func (t *T) New(...interface{}) interface{} {
+	rt, err := Default.Lookup(t.Proto)
+	if err != nil {
+		return nil
+	}
+	return reflect.New(rt).Interface()
+}
+
+// 由注册表 r 查找类型, 生成 p 对应的实例.
+// p 是 *Struct 或 *Fn 时分别同 Struct.Make 和 Fn.Make, 只是以 r 代替默认注册表,
+// 其它描述返回指向零值的指针.
+func (r *Registry) Make(p ProtoType, args ...interface{}) (interface{}, error) {
+	switch p := p.(type) {
+	case *Struct:
+		return p.make(r, args)
+	case *Fn:
+		return p.make(r, args)
+	}
+	rt, err := r.Lookup(protoOf(p))
+	if err != nil {
+		return nil, err
+	}
+	return reflect.New(rt).Interface(), nil
+}
+
+// 以字段名进行初始化, 配合 Struct.New 使用
+//
+//	s.New(Values{"Name": "a"})
+type Values map[string]interface{}
+
+// 生成 struct 实例, 返回指向实例的指针.
+// 如果默认注册表中注册了此命名类型, 使用 reflect.New 生成, 否则使用 reflect.StructOf 生成.
+// args 按字段顺序进行初始化, 或者是一个 Values 以字段名进行初始化.
+// 失败时抛出 panic, 值为 Make 返回的 error.
+func (s *Struct) New(args ...interface{}) interface{} {
+	v, err := s.Make(args...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// 同 New, 失败时返回 error, error 中包含字段名和期待的 proto 类型
+func (s *Struct) Make(args ...interface{}) (interface{}, error) {
+	return s.make(Default, args)
+}
+
+func (s *Struct) make(r *Registry, args []interface{}) (interface{}, error) {
+	t, err := s.rtype(r)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(t).Elem()
+	if len(args) == 1 {
+		if vs, ok := args[0].(Values); ok {
+			for name, arg := range vs {
+				f, ok := t.FieldByName(name)
+				if !ok || len(f.Index) != 1 {
+					return nil, toNewFailed("field ", name, " not found in ", s.Proto)
+				}
+				if err = setField(v, f.Index[0], arg); err != nil {
+					return nil, err
+				}
+			}
+			return v.Addr().Interface(), nil
+		}
+	}
+	if len(args) > t.NumField() {
+		return nil, toNewFailed("too many values for ", s.Proto)
+	}
+	for i, arg := range args {
+		if err = setField(v, i, arg); err != nil {
+			return nil, err
+		}
+	}
+	return v.Addr().Interface(), nil
+}
+
+func setField(v reflect.Value, i int, arg interface{}) error {
+	f := v.Type().Field(i)
+	fv := v.Field(i)
+	if !fv.CanSet() {
+		return toNewFailed("field ", f.Name, ": cannot set unexported field")
+	}
+	if arg == nil {
+		fv.Set(reflect.Zero(f.Type))
+		return nil
+	}
+	a := reflect.ValueOf(arg)
+	if !a.Type().AssignableTo(f.Type) {
+		return toNewFailed("field ", f.Name, ": want ", prototype(f.Type), ", got ", prototype(a.Type()))
+	}
+	fv.Set(a)
+	return nil
+}
+
+// 返回 Struct 对应的 reflect.Type, 命名类型未注册时使用 reflect.StructOf 生成
+func (s *Struct) rtype(r *Registry) (reflect.Type, error) {
+	if s.Name != "" {
+		if t, err := r.Lookup(s.Proto); err == nil {
+			return t, nil
+		}
+	}
+	pkg := ""
+	if i := strings.LastIndexByte(s.Proto, '.'); s.Name != "" && i != -1 {
+		pkg = s.Proto[:i]
+	}
+	fs := make([]reflect.StructField, len(s.Fields))
+	for i, f := range s.Fields {
+		t, err := r.Lookup(f.Proto)
+		if err != nil {
+			return nil, toNewFailed("field ", f.Name, ": ", err)
+		}
+		fs[i] = reflect.StructField{
+			Name: f.Name, Type: t, Tag: reflect.StructTag(f.Tag), Anonymous: f.Embedded,
+		}
+		if !f.Exported {
+			if pkg == "" {
+				return nil, toNewFailed("field ", f.Name, ": unexported field needs a named struct")
+			}
+			fs[i].PkgPath = pkg
+		}
+	}
+	return structOf(fs)
+}
+
+func structOf(fs []reflect.StructField) (t reflect.Type, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = toNewFailed(e)
+		}
+	}()
+	return reflect.StructOf(fs), nil
+}
+
+// 生成函数, 参数 handler 是函数的实现, 可以是
+//
+//	func([]reflect.Value) []reflect.Value
+//	func([]interface{}) []interface{}
+//
+// 后者返回的 nil 被当作零值.
+// 函数类型优先使用默认注册表查找, 否则由 In, Out 生成.
+// 失败时抛出 panic, 值为 Make 返回的 error.
+func (f *Fn) New(handler ...interface{}) interface{} {
+	v, err := f.Make(handler...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// 同 New, 失败时返回 error
+func (f *Fn) Make(handler ...interface{}) (interface{}, error) {
+	return f.make(Default, handler)
+}
+
+func (f *Fn) make(r *Registry, handler []interface{}) (interface{}, error) {
+	t, err := f.rtype(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(handler) != 1 {
+		return nil, toNewFailed("want one handler for ", f.Proto)
+	}
+	switch fn := handler[0].(type) {
+	case func([]reflect.Value) []reflect.Value:
+		return reflect.MakeFunc(t, fn).Interface(), nil
+	case func([]interface{}) []interface{}:
+		return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+			args := make([]interface{}, len(in))
+			for i, v := range in {
+				args[i] = v.Interface()
+			}
+			res := fn(args)
+			if len(res) != t.NumOut() {
+				panic(toNewFailed("want ", t.NumOut(), " results for ", f.Proto, ", got ", len(res)))
+			}
+			out := make([]reflect.Value, len(res))
+			for i, r := range res {
+				ot := t.Out(i)
+				if r == nil {
+					out[i] = reflect.Zero(ot)
+					continue
+				}
+				out[i] = reflect.ValueOf(r)
+				if !out[i].Type().AssignableTo(ot) {
+					panic(toNewFailed("result ", i, ": want ", prototype(ot), ", got ", prototype(out[i].Type())))
+				}
+				// MakeFunc 要求返回值类型完全一致
+				out[i] = out[i].Convert(ot)
+			}
+			return out
+		}).Interface(), nil
+	}
+	return nil, toNewFailed("invalid handler ", prototype(TypeOf(handler[0])))
+}
+
+// 返回 Fn 对应的 reflect.Type, 未注册时由 In, Out 生成
+func (f *Fn) rtype(r *Registry) (reflect.Type, error) {
+	if t, err := r.Lookup(f.Proto); err == nil {
+		return t, nil
+	}
+	in := make([]reflect.Type, len(f.In))
+	out := make([]reflect.Type, len(f.Out))
+	var err error
+	for i, a := range f.In {
+		if in[i], err = r.Lookup(a.Proto); err != nil {
+			return nil, toNewFailed("param ", i, ": ", err)
+		}
+	}
+	for i, a := range f.Out {
+		if out[i], err = r.Lookup(a.Proto); err != nil {
+			return nil, toNewFailed("result ", i, ": ", err)
+		}
+	}
+	if f.Variadic && (len(in) == 0 || in[len(in)-1].Kind() != reflect.Slice) {
+		return nil, toNewFailed("invalid variadic ", f.Proto)
+	}
+	return reflect.FuncOf(in, out, f.Variadic), nil
+}
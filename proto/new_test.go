@@ -0,0 +1,98 @@
+package proto_test
+
+import (
+	"errors"
+	"github.com/gohub/typeless/proto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStructNew(T *testing.T) {
+	s := proto.Describe(User{}).(*proto.Struct)
+	r := &proto.Registry{}
+
+	// User 未注册, 由 reflect.StructOf 生成
+	r.Register(Base{})
+	v, err := r.Make(s, proto.Values{"Name": "a", "Tags": []string{"b"}})
+	rv := reflect.ValueOf(v).Elem()
+	if err != nil || rv.Type() == reflect.TypeOf(User{}) || rv.FieldByName("Name").String() != "a" {
+		T.Errorf("bad struct %#v %v", v, err)
+	}
+
+	r.Register(User{})
+	v, err = r.Make(s, Base{1}, "a")
+	u, ok := v.(*User)
+	if !ok || u.ID != 1 || u.Name != "a" {
+		T.Errorf("bad user %#v %v", v, err)
+	}
+
+	// 基础类型的字段无需注册
+	p := proto.Describe(struct{ N int }{}).(*proto.Struct)
+	if v := p.New(1); reflect.ValueOf(v).Elem().Field(0).Int() != 1 {
+		T.Errorf("bad struct %#v", v)
+	}
+}
+
+func TestRegistryMake(T *testing.T) {
+	r := &proto.Registry{}
+	p := proto.Describe([]Base{})
+	if _, err := r.Make(p); err == nil {
+		T.Error("want an error")
+	}
+	r.Register(Base{})
+	v, err := r.Make(p)
+	if _, ok := v.(*[]Base); !ok || err != nil {
+		T.Errorf("bad slice %#v %v", v, err)
+	}
+}
+
+func TestStructMakeError(T *testing.T) {
+	s := proto.Describe(User{}).(*proto.Struct)
+	r := &proto.Registry{}
+	r.Register(Base{})
+	for _, args := range [][]interface{}{
+		{Base{}, 1},
+		{proto.Values{"Name": 1}},
+		{proto.Values{"None": 1}},
+		{proto.Values{"email": "a"}},
+		{Base{}, "a", nil, "b", "c"},
+	} {
+		_, err := r.Make(s, args...)
+		if err == nil {
+			T.Errorf("want an error for %#v", args)
+		}
+	}
+	_, err := r.Make(s, Base{}, 1)
+	if err == nil || !strings.Contains(err.Error(), "field Name: want string, got int") {
+		T.Errorf("bad error %v", err)
+	}
+}
+
+func TestFnNew(T *testing.T) {
+	fn := proto.Describe(func(string, ...int) (int, error) { return 0, nil }).(*proto.Fn)
+	f, ok := fn.New(func(in []interface{}) []interface{} {
+		n := len(in[0].(string))
+		for _, i := range in[1].([]int) {
+			n += i
+		}
+		return []interface{}{n, nil}
+	}).(func(string, ...int) (int, error))
+	if !ok {
+		T.Fatal("bad func type")
+	}
+	if n, err := f("abc", 1, 2); n != 6 || err != nil {
+		T.Errorf("want 6, but %v %v", n, err)
+	}
+
+	e := proto.Describe(func() error { return nil }).(*proto.Fn)
+	g := e.New(func(in []reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(errors.New("x"))}
+	}).(func() error)
+	if err := g(); err == nil || err.Error() != "x" {
+		T.Errorf("want error x, but %v", err)
+	}
+	if _, err := e.Make(1); err == nil {
+		T.Error("want an error")
+	}
+}
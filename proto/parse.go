@@ -0,0 +1,543 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Expr 的种类
+type Kind uint8
+
+const (
+	KindInvalid   Kind = iota
+	KindNil            // nil
+	KindIdent          // 内置类型或命名类型, 例如 int, error, net/http.Request
+	KindArray          // [N]Elem
+	KindSlice          // []Elem
+	KindMap            // map[Key]Elem
+	KindPtr            // *Elem
+	KindChan           // chan Elem, <-chan Elem, chan<- Elem
+	KindFunc           // func(In) Out
+	KindStruct         // struct { Fields }
+	KindInterface      // interface{}, interface { Methods }
+)
+
+var kindNames = []string{
+	KindInvalid:   "invalid",
+	KindNil:       "nil",
+	KindIdent:     "ident",
+	KindArray:     "array",
+	KindSlice:     "slice",
+	KindMap:       "map",
+	KindPtr:       "ptr",
+	KindChan:      "chan",
+	KindFunc:      "func",
+	KindStruct:    "struct",
+	KindInterface: "interface",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "kind" + strconv.Itoa(int(k))
+}
+
+// 匿名 struct 的字段, 或者匿名 interface 的方法
+type ExprField struct {
+	PkgPath  string // 非导出字段的 PkgPath 只出现在 StrictStruct 模式的描述中, 非导出方法总是有 PkgPath
+	Name     string
+	Type     *Expr
+	Tag      string
+	Embedded bool // 嵌入字段, 只写类型
+}
+
+// Expr 是 proto 描述的语法树, 由 Parse 生成, String 方法还原 proto 描述.
+type Expr struct {
+	Kind     Kind
+	PkgPath  string          // Ident 的 PkgPath, 内置类型为空
+	Name     string          // Ident 的名称, 不包含类型参数
+	Args     []*Expr         // Ident 的类型参数, 例如 example.com/x.List[int]
+	Len      int             // Array 的长度
+	Dir      reflect.ChanDir // Chan 的方向
+	Key      *Expr           // Map 的 Key
+	Elem     *Expr           // Array, Slice, Map, Ptr, Chan 的元素
+	In, Out  []*Expr         // Func 的参数和返回值
+	Variadic bool            // Func 的最后一个参数是否是可变参数, 此时 In 的最后一个是元素类型
+	Fields   []ExprField     // Struct 的字段
+	Methods  []ExprField     // Interface 的方法, 按名称排序, Type 是 Func
+	Spaced   bool            // 空 Interface 写作 interface {}, 即 KeepInterface 模式的描述
+}
+
+func toSyntax(s string, pos int, msg ...interface{}) error {
+	return errors.New("proto syntax error: " + fmt.Sprint(msg...) +
+		" at " + strconv.Itoa(pos) + " in " + strconv.Quote(s))
+}
+
+// 解析 proto 描述, 生成 Expr. 格式与 Type 的结果一致
+func Parse(s string) (*Expr, error) {
+	p := &parser{s: s}
+	x, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(s) {
+		return nil, toSyntax(s, p.pos, "unexpected ", strconv.Quote(s[p.pos:]))
+	}
+	return x, nil
+}
+
+// 同 Parse, 失败时抛出 panic
+func MustParse(s string) *Expr {
+	x, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+type parser struct {
+	s       string
+	pos     int
+	pattern bool // 模式语法, 参数和返回值列表的最后可以是 "..."
+}
+
+func (p *parser) fail(msg ...interface{}) error {
+	return toSyntax(p.s, p.pos, msg...)
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// 如果剩余部分以 prefix 开头, 消耗掉 prefix
+func (p *parser) got(prefix string) bool {
+	if strings.HasPrefix(p.s[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(prefix string) error {
+	if !p.got(prefix) {
+		return p.fail("expected ", strconv.Quote(prefix))
+	}
+	return nil
+}
+
+// 关键字后必须是分隔符
+func (p *parser) keyword(kw string) bool {
+	if !strings.HasPrefix(p.s[p.pos:], kw) {
+		return false
+	}
+	end := p.pos + len(kw)
+	if end < len(p.s) && isNameByte(p.s[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func isNameByte(c byte) bool {
+	switch c {
+	case ' ', ',', ';', '(', ')', '[', ']', '{', '}', '*', '"':
+		return false
+	}
+	return true
+}
+
+// 列表分隔符, reflect 的类型参数列表中没有空格
+func (p *parser) comma() error {
+	if err := p.expect(","); err != nil {
+		return err
+	}
+	p.skipSpace()
+	return nil
+}
+
+func (p *parser) parseType() (x *Expr, err error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return nil, p.fail("unexpected end")
+	}
+	switch {
+	case p.got("*"):
+		x = &Expr{Kind: KindPtr}
+		x.Elem, err = p.parseType()
+	case p.got("[]"):
+		x = &Expr{Kind: KindSlice}
+		x.Elem, err = p.parseType()
+	case p.got("["):
+		x = &Expr{Kind: KindArray}
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		x.Len, err = strconv.Atoi(p.s[start:p.pos])
+		if err != nil {
+			return nil, p.fail("invalid array length")
+		}
+		if err = p.expect("]"); err == nil {
+			x.Elem, err = p.parseType()
+		}
+	case p.got("map["):
+		x = &Expr{Kind: KindMap}
+		if x.Key, err = p.parseType(); err != nil {
+			return
+		}
+		if err = p.expect("]"); err == nil {
+			x.Elem, err = p.parseType()
+		}
+	case p.got("<-chan "):
+		x = &Expr{Kind: KindChan, Dir: reflect.RecvDir}
+		x.Elem, err = p.parseType()
+	case p.got("chan<- "):
+		x = &Expr{Kind: KindChan, Dir: reflect.SendDir}
+		x.Elem, err = p.parseType()
+	case p.got("chan "):
+		x = &Expr{Kind: KindChan, Dir: reflect.BothDir}
+		x.Elem, err = p.parseType()
+	case p.got("func("):
+		x = &Expr{Kind: KindFunc}
+		err = p.parseFunc(x)
+	case p.got("struct {"):
+		x = &Expr{Kind: KindStruct}
+		err = p.parseStruct(x)
+	case p.got("interface{}"):
+		x = &Expr{Kind: KindInterface}
+	case p.got("interface {}"):
+		x = &Expr{Kind: KindInterface, Spaced: true}
+	case p.got("interface {"):
+		x = &Expr{Kind: KindInterface}
+		err = p.parseInterface(x)
+	case p.keyword("nil"):
+		x = &Expr{Kind: KindNil}
+	default:
+		x, err = p.parseIdent()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (p *parser) parseIdent() (*Expr, error) {
+	start := p.pos
+	for p.pos < len(p.s) && isNameByte(p.s[p.pos]) {
+		p.pos++
+	}
+	name := p.s[start:p.pos]
+	if name == "" {
+		return nil, p.fail("unexpected ", strconv.Quote(p.s[p.pos:p.pos+1]))
+	}
+	x := &Expr{Kind: KindIdent, Name: name}
+	// PkgPath 可能包含 '.', 比如 gopkg.in/yaml.v2.Node, 名称在最后一个 '.' 之后
+	if i := strings.LastIndexByte(name, '.'); i != -1 {
+		x.PkgPath, x.Name = name[:i], name[i+1:]
+		if x.Name == "" || x.PkgPath == "" {
+			return nil, toSyntax(p.s, start, "invalid name ", strconv.Quote(name))
+		}
+	}
+	// 类型参数
+	if !p.got("[") {
+		return x, nil
+	}
+	for !p.got("]") {
+		if len(x.Args) != 0 {
+			if err := p.comma(); err != nil {
+				return nil, err
+			}
+		}
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		x.Args = append(x.Args, t)
+	}
+	if len(x.Args) == 0 {
+		return nil, p.fail("empty type arguments")
+	}
+	return x, nil
+}
+
+func (p *parser) parseFunc(x *Expr) (err error) {
+	var t *Expr
+	for !p.got(")") {
+		if len(x.In) != 0 {
+			if err = p.comma(); err != nil {
+				return
+			}
+		}
+		if x.Variadic {
+			return p.fail("variadic parameter must be last")
+		}
+		if p.rest() {
+			x.In = append(x.In, &Expr{Kind: KindIdent, Name: anyRest})
+			continue
+		}
+		x.Variadic = p.got("...")
+		if t, err = p.parseType(); err != nil {
+			return
+		}
+		x.In = append(x.In, t)
+	}
+	// 返回值, 后面紧跟分隔符, " }" 或者字段的 tag 表示没有返回值
+	rest := p.s[p.pos:]
+	if !strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, " }") || strings.HasPrefix(rest, ` "`) {
+		return
+	}
+	p.pos++
+	if !p.got("(") {
+		t, err = p.parseType()
+		x.Out = append(x.Out, t)
+		return
+	}
+	for !p.got(")") {
+		if len(x.Out) != 0 {
+			if err = p.comma(); err != nil {
+				return
+			}
+		}
+		if p.rest() {
+			t = &Expr{Kind: KindIdent, Name: anyRest}
+		} else if t, err = p.parseType(); err != nil {
+			return
+		}
+		x.Out = append(x.Out, t)
+	}
+	if len(x.Out) == 0 || len(x.Out) == 1 && !isRest(x.Out[0]) {
+		return p.fail("parenthesized results need at least two types")
+	}
+	return
+}
+
+// 模式语法中列表最后的 "...", 表示任意数量的剩余类型
+func (p *parser) rest() bool {
+	if p.pattern && strings.HasPrefix(p.s[p.pos:], anyRest+")") {
+		p.pos += len(anyRest)
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseStruct(x *Expr) (err error) {
+	p.skipSpace()
+	for !p.got("}") {
+		if len(x.Fields) != 0 {
+			if err = p.expect(";"); err != nil {
+				return
+			}
+			p.skipSpace()
+		}
+		f := ExprField{}
+		t, err := p.parseType()
+		if err != nil {
+			return err
+		}
+		rest := p.s[p.pos:]
+		if strings.HasPrefix(rest, ";") || strings.HasPrefix(rest, "}") ||
+			strings.HasPrefix(rest, " }") || strings.HasPrefix(rest, " \"") {
+			// 嵌入字段, 名称为类型名
+			f.Embedded, f.Type = true, t
+			if t.Kind == KindPtr {
+				t = t.Elem
+			}
+			if t.Kind != KindIdent {
+				return p.fail("invalid embedded field")
+			}
+			f.Name = t.Name
+		} else {
+			if t.Kind != KindIdent || len(t.Args) != 0 {
+				return p.fail("expected field name")
+			}
+			f.PkgPath, f.Name = t.PkgPath, t.Name
+			if f.Type, err = p.parseType(); err != nil {
+				return err
+			}
+		}
+		if strings.HasPrefix(p.s[p.pos:], " \"") {
+			p.pos++
+			q, err := strconv.QuotedPrefix(p.s[p.pos:])
+			if err != nil {
+				return p.fail("invalid tag")
+			}
+			f.Tag, _ = strconv.Unquote(q)
+			p.pos += len(q)
+		}
+		x.Fields = append(x.Fields, f)
+		p.skipSpace()
+	}
+	return
+}
+
+func (p *parser) parseInterface(x *Expr) error {
+	p.skipSpace()
+	for !p.got("}") {
+		if len(x.Methods) != 0 {
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+			p.skipSpace()
+		}
+		start := p.pos
+		for p.pos < len(p.s) && isNameByte(p.s[p.pos]) {
+			p.pos++
+		}
+		name := &Expr{}
+		if start != p.pos {
+			name, _ = (&parser{s: p.s[start:p.pos]}).parseIdent()
+		}
+		if name.Name == "" {
+			return p.fail("expected method name")
+		}
+		if err := p.expect("("); err != nil {
+			return err
+		}
+		m := ExprField{PkgPath: name.PkgPath, Name: name.Name, Type: &Expr{Kind: KindFunc}}
+		if err := p.parseFunc(m.Type); err != nil {
+			return err
+		}
+		x.Methods = append(x.Methods, m)
+		p.skipSpace()
+	}
+	return nil
+}
+
+// 还原 proto 描述, 对于 Parse 的结果, 与原字符串完全一致
+func (x *Expr) String() string {
+	b := []byte{}
+	return string(x.appendTo(b, nil, false))
+}
+
+// q 非空时返回 PkgPath 的替代写法, 返回空表示不需要限定, code 表示生成 Go 代码形式
+func (x *Expr) appendTo(b []byte, q func(string) string, code bool) []byte {
+	if x == nil {
+		return append(b, "nil"...)
+	}
+	switch x.Kind {
+	case KindNil:
+		return append(b, "nil"...)
+	case KindIdent:
+		p := x.PkgPath
+		if q != nil && p != "" {
+			p = q(p)
+		}
+		if p != "" {
+			b = append(b, p...)
+			b = append(b, '.')
+		}
+		b = append(b, x.Name...)
+		if len(x.Args) != 0 {
+			b = append(b, '[')
+			for i, t := range x.Args {
+				if i != 0 {
+					b = append(b, ", "...)
+				}
+				b = t.appendTo(b, q, code)
+			}
+			b = append(b, ']')
+		}
+		return b
+	case KindArray:
+		b = append(b, '[')
+		b = strconv.AppendInt(b, int64(x.Len), 10)
+		b = append(b, ']')
+		return x.Elem.appendTo(b, q, code)
+	case KindSlice:
+		return x.Elem.appendTo(append(b, "[]"...), q, code)
+	case KindMap:
+		b = x.Key.appendTo(append(b, "map["...), q, code)
+		return x.Elem.appendTo(append(b, ']'), q, code)
+	case KindPtr:
+		return x.Elem.appendTo(append(b, '*'), q, code)
+	case KindChan:
+		b = append(b, x.Dir.String()...)
+		// Go 代码中 chan <-chan T 会被解析为 chan<- chan T
+		if code && x.Dir == reflect.BothDir && x.Elem.Kind == KindChan && x.Elem.Dir == reflect.RecvDir {
+			b = x.Elem.appendTo(append(b, " ("...), q, code)
+			return append(b, ')')
+		}
+		return x.Elem.appendTo(append(b, ' '), q, code)
+	case KindFunc:
+		b = append(b, "func("...)
+		for i, t := range x.In {
+			if i != 0 {
+				b = append(b, ", "...)
+			}
+			if x.Variadic && i == len(x.In)-1 {
+				b = append(b, "..."...)
+			}
+			b = t.appendTo(b, q, code)
+		}
+		b = append(b, ')')
+		if len(x.Out) == 1 {
+			return x.Out[0].appendTo(append(b, ' '), q, code)
+		}
+		if len(x.Out) > 1 {
+			b = append(b, " ("...)
+			for i, t := range x.Out {
+				if i != 0 {
+					b = append(b, ", "...)
+				}
+				b = t.appendTo(b, q, code)
+			}
+			b = append(b, ')')
+		}
+		return b
+	case KindStruct:
+		if len(x.Fields) == 0 {
+			return append(b, "struct {}"...)
+		}
+		b = append(b, "struct { "...)
+		for i, f := range x.Fields {
+			if i != 0 {
+				b = append(b, "; "...)
+			}
+			if f.Embedded {
+				b = f.Type.appendTo(b, q, code)
+			} else {
+				// Go 代码中字段名不能限定
+				if f.PkgPath != "" && !code {
+					b = append(b, f.PkgPath...)
+					b = append(b, '.')
+				}
+				b = append(b, f.Name...)
+				b = f.Type.appendTo(append(b, ' '), q, code)
+			}
+			if f.Tag != "" {
+				b = strconv.AppendQuote(append(b, ' '), f.Tag)
+			}
+		}
+		return append(b, " }"...)
+	case KindInterface:
+		if len(x.Methods) == 0 && x.Spaced {
+			return append(b, "interface {}"...)
+		}
+		if len(x.Methods) == 0 {
+			return append(b, "interface{}"...)
+		}
+		b = append(b, "interface { "...)
+		for i, m := range x.Methods {
+			if i != 0 {
+				b = append(b, "; "...)
+			}
+			if m.PkgPath != "" && !code {
+				b = append(b, m.PkgPath...)
+				b = append(b, '.')
+			}
+			b = append(b, m.Name...)
+			// 签名去掉 func
+			n := len(b)
+			b = m.Type.appendTo(b, q, code)
+			b = append(b[:n], b[n+len("func"):]...)
+		}
+		return append(b, " }"...)
+	}
+	return append(b, "invalid"...)
+}
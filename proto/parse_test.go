@@ -0,0 +1,76 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseRoundTrip(T *testing.T) {
+	corpus := append(append([]interface{}{}, Builtins...), Funs...)
+	corpus = append(corpus,
+		http.HandlerFunc(nil), make(chan<- []int), make(<-chan map[string]*http.Request),
+		make(chan (<-chan int)), [2][]struct{ F func() }{}, struct{}{},
+		func(...int) (int, error) { return 0, nil }, func(func() int, string) {},
+		struct {
+			A string
+			F func(int) (bool, error)
+		}{},
+	)
+	for _, k := range corpus {
+		s := proto.Type(k)
+		x, err := proto.Parse(s)
+		if err != nil {
+			T.Errorf("%s: %v", s, err)
+			continue
+		}
+		if x.String() != s {
+			T.Errorf("want: %s\n got: %s", s, x.String())
+		}
+	}
+}
+
+// 两种空接口的写法都保持原样
+func TestParseInterfaceSpelling(T *testing.T) {
+	for _, s := range []string{
+		"map[string]interface {}",
+		"func(interface {}, ...interface{}) interface {}",
+		(&proto.Printer{Mode: proto.KeepInterface}).Type([]interface{}{}),
+	} {
+		if got := proto.MustParse(s).String(); got != s {
+			T.Errorf("want: %s\n got: %s", s, got)
+		}
+	}
+}
+
+func TestParseExpr(T *testing.T) {
+	x := proto.MustParse("func(net/http.ResponseWriter, ...*gopkg.in/yaml.v2.Node) (map[string]chan<- int, error)")
+	want := &proto.Expr{
+		Kind: proto.KindFunc,
+		In: []*proto.Expr{
+			{Kind: proto.KindIdent, PkgPath: "net/http", Name: "ResponseWriter"},
+			{Kind: proto.KindPtr, Elem: &proto.Expr{Kind: proto.KindIdent, PkgPath: "gopkg.in/yaml.v2", Name: "Node"}},
+		},
+		Variadic: true,
+		Out: []*proto.Expr{
+			{Kind: proto.KindMap, Key: &proto.Expr{Kind: proto.KindIdent, Name: "string"},
+				Elem: &proto.Expr{Kind: proto.KindChan, Dir: reflect.SendDir, Elem: &proto.Expr{Kind: proto.KindIdent, Name: "int"}}},
+			{Kind: proto.KindIdent, Name: "error"},
+		},
+	}
+	if !reflect.DeepEqual(x, want) {
+		T.Errorf("want: %#v\n got: %#v", want, x)
+	}
+}
+
+func TestParseError(T *testing.T) {
+	for _, s := range []string{
+		"", "[x]int", "map[string", "func(int", "func() (int)", "func(...int, string)",
+		"struct { []int }", "int int", "*", ".T",
+	} {
+		if _, err := proto.Parse(s); err == nil {
+			T.Errorf("want an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package proto
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Printer 的生成模式
+type Mode uint
+
+const (
+	KeepError     Mode = 1 << iota // 实现了 error 的值描述为其动态类型, 而非 error
+	KeepInterface                  // interface{} 描述为 interface {}, 与 reflect 一致
+	FoldStruct                     // 匿名 struct 不展开字段, 描述为 struct {...}
+	VariadicSlice                  // 可变参数描述为 []T, 而非 ...T
+	// 匿名 struct 包含 tag, 嵌入字段只写类型, 非导出字段名以 PkgPath 限定,
+	// 例如 struct { io.Reader; example.com/x.a string "json:\"a\"" }.
+	// 此模式下描述相同即类型相同.
+	StrictStruct
+)
+
+// Printer 是可配置的 proto 描述生成器. 零值 Printer 的结果与 Type 一致.
+type Printer struct {
+	Mode Mode
+	// 返回 PkgPath 的替代写法, 返回空字符串表示不限定包名.
+	// 为 nil 时使用完整的 PkgPath.
+	Qualifier func(pkgPath string) string
+}
+
+// 默认配置, Type, Types, Join 使用的 Printer
+var std = &Printer{}
+
+// 以包名限定, 例如 net/http.Request 描述为 http.Request
+func ShortQualifier(pkgPath string) string {
+	return packageName(pkgPath)
+}
+
+// 去掉 vendor 目录之前的部分, 例如 example.com/m/vendor/golang.org/x/net 为 golang.org/x/net
+func VendorQualifier(pkgPath string) string {
+	if i := strings.LastIndex(pkgPath, "/vendor/"); i != -1 {
+		return pkgPath[i+len("/vendor/"):]
+	}
+	return strings.TrimPrefix(pkgPath, "vendor/")
+}
+
+// 返回以 go.mod 中 module 为前缀的 PkgPath 转换为相对路径的 Qualifier,
+// 例如 module 为 example.com/m 时, example.com/m/x.T 描述为 x.T, example.com/m.T 描述为 T.
+// 其他的 PkgPath 保持不变.
+func ModuleQualifier(module string) func(string) string {
+	return func(pkgPath string) string {
+		if pkgPath == module {
+			return ""
+		}
+		if strings.HasPrefix(pkgPath, module+"/") {
+			return pkgPath[len(module)+1:]
+		}
+		return pkgPath
+	}
+}
+
+// 返回 proto 描述
+func (p *Printer) Type(x interface{}) string {
+	if x == nil {
+		return "nil"
+	}
+	if _, ok := x.(error); ok && p.Mode&KeepError == 0 {
+		return "error"
+	}
+	return p.typeOf(TypeOf(x))
+}
+
+// 返回多个 interface{} 的 proto []string
+func (p *Printer) Types(in ...interface{}) []string {
+	ss := make([]string, len(in))
+	for i, v := range in {
+		ss[i] = p.Type(v)
+	}
+	return ss
+}
+
+// 生成 proto 描述,并用 ", " 连接
+func (p *Printer) Join(in ...interface{}) string {
+	return strings.Join(p.Types(in...), ", ")
+}
+
+// 带有包名限定的名称
+func (p *Printer) qualify(pkgPath, name string) string {
+	if pkgPath == "" {
+		return name
+	}
+	if p.Qualifier != nil {
+		pkgPath = p.Qualifier(pkgPath)
+		if pkgPath == "" {
+			return name
+		}
+	}
+	return pkgPath + "." + name
+}
+
+// 默认配置的描述缓存, reflect.Type -> string
+var cache sync.Map
+
+// 返回 t 的描述, 默认配置使用缓存
+func (p *Printer) typeOf(t reflect.Type) string {
+	if p != std {
+		return p.typeString(t)
+	}
+	if s, ok := cache.Load(t); ok {
+		return s.(string)
+	}
+	s := p.typeString(t)
+	cache.Store(t, s)
+	return s
+}
+
+func (p *Printer) typeString(t reflect.Type) string {
+	var b strings.Builder
+	p.write(&b, t)
+	return b.String()
+}
+
+func (p *Printer) write(b *strings.Builder, t reflect.Type) {
+	k := t.Kind()
+	if name := t.Name(); name != "" {
+		pp := t.PkgPath()
+		if k == reflect.UnsafePointer || pp == "" {
+			// 内置类型
+			b.WriteString(t.String())
+			return
+		}
+		if i := strings.IndexByte(name, '['); i != -1 {
+			name = name[:i] + p.typeArgs(name[i:])
+		}
+		b.WriteString(p.qualify(pp, name))
+		return
+	}
+	switch k {
+	case reflect.Array:
+		b.WriteByte('[')
+		b.WriteString(strconv.Itoa(t.Len()))
+		b.WriteByte(']')
+		p.write(b, t.Elem())
+	case reflect.Chan:
+		b.WriteString(t.ChanDir().String())
+		b.WriteByte(' ')
+		p.write(b, t.Elem())
+	case reflect.Func:
+		b.WriteString("func")
+		p.writeSignature(b, t)
+	case reflect.Map:
+		b.WriteString("map[")
+		p.write(b, t.Key())
+		b.WriteByte(']')
+		p.write(b, t.Elem())
+	case reflect.Ptr:
+		b.WriteByte('*')
+		p.write(b, t.Elem())
+	case reflect.Slice:
+		b.WriteString("[]")
+		p.write(b, t.Elem())
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			if p.Mode&KeepInterface != 0 {
+				b.WriteString("interface {}")
+			} else {
+				b.WriteString("interface{}")
+			}
+			return
+		}
+		// 按名称排序的方法集, 非导出方法以 PkgPath 限定
+		ms := make([]reflect.Method, t.NumMethod())
+		for i := range ms {
+			ms[i] = t.Method(i)
+		}
+		sort.Slice(ms, func(i, j int) bool { return ms[i].Name < ms[j].Name })
+		b.WriteString("interface { ")
+		for i, m := range ms {
+			if i != 0 {
+				b.WriteString("; ")
+			}
+			b.WriteString(p.qualify(m.PkgPath, m.Name))
+			p.writeSignature(b, m.Type)
+		}
+		b.WriteString(" }")
+	case reflect.Struct:
+		if p.Mode&FoldStruct != 0 {
+			b.WriteString("struct {...}")
+			return
+		}
+		if t.NumField() == 0 {
+			b.WriteString("struct {}")
+			return
+		}
+		strict := p.Mode&StrictStruct != 0
+		b.WriteString("struct { ")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if i != 0 {
+				b.WriteString("; ")
+			}
+			if strict && f.Anonymous {
+				p.write(b, f.Type)
+			} else {
+				if strict {
+					b.WriteString(p.qualify(f.PkgPath, f.Name))
+				} else {
+					b.WriteString(f.Name)
+				}
+				b.WriteByte(' ')
+				p.write(b, f.Type)
+			}
+			if strict && f.Tag != "" {
+				b.WriteByte(' ')
+				b.WriteString(strconv.Quote(string(f.Tag)))
+			}
+		}
+		b.WriteString(" }")
+	default:
+		b.WriteString(t.String())
+	}
+}
+
+// 不包含 func 的签名
+func (p *Printer) writeSignature(b *strings.Builder, t reflect.Type) {
+	b.WriteByte('(')
+	max := t.NumIn() - 1
+	for i := 0; i <= max; i++ {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		if i == max && t.IsVariadic() && p.Mode&VariadicSlice == 0 {
+			b.WriteString("...")
+			p.write(b, t.In(i).Elem())
+		} else {
+			p.write(b, t.In(i))
+		}
+	}
+	b.WriteByte(')')
+	switch t.NumOut() {
+	case 0:
+		return
+	case 1:
+		b.WriteByte(' ')
+		p.write(b, t.Out(0))
+		return
+	}
+	b.WriteString(" (")
+	for i := 0; i < t.NumOut(); i++ {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		p.write(b, t.Out(i))
+	}
+	b.WriteByte(')')
+}
+
+// 泛型实例的类型参数, 例如 [example.com/y.Item], reflect 已经使用完整的 PkgPath,
+// 这里按照 Printer 的配置统一为 proto 的写法, 解析失败时原样返回
+func (p *Printer) typeArgs(s string) string {
+	x, err := Parse("_" + s)
+	if err != nil {
+		return s
+	}
+	p.normalize(x)
+	return string(x.appendTo(nil, p.Qualifier, false)[1:])
+}
+
+// 按照 Mode 调整由 reflect 的写法解析得到的 x, 使其与顶层类型的描述一致.
+// reflect 的写法相当于 StrictStruct 和 KeepInterface 模式.
+func (p *Printer) normalize(x *Expr) {
+	if x == nil {
+		return
+	}
+	p.normalize(x.Key)
+	p.normalize(x.Elem)
+	for _, xs := range [][]*Expr{x.Args, x.In, x.Out} {
+		for _, a := range xs {
+			p.normalize(a)
+		}
+	}
+	for _, fs := range [][]ExprField{x.Fields, x.Methods} {
+		for _, f := range fs {
+			p.normalize(f.Type)
+		}
+	}
+	switch x.Kind {
+	case KindInterface:
+		// reflect 把空接口写作 interface {}
+		x.Spaced = len(x.Methods) == 0 && p.Mode&KeepInterface != 0
+	case KindStruct:
+		if p.Mode&FoldStruct != 0 {
+			// 只用于输出, 以名称表示折叠的 struct
+			*x = Expr{Kind: KindIdent, Name: "struct {...}"}
+			return
+		}
+		if p.Mode&StrictStruct == 0 {
+			for i := range x.Fields {
+				f := &x.Fields[i]
+				f.PkgPath, f.Tag, f.Embedded = "", "", false
+			}
+		}
+	case KindFunc:
+		if x.Variadic && p.Mode&VariadicSlice != 0 {
+			last := len(x.In) - 1
+			x.In[last] = &Expr{Kind: KindSlice, Elem: x.In[last]}
+			x.Variadic = false
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package proto_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gohub/typeless/proto"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPrinter(T *testing.T) {
+	fn := func(http.ResponseWriter, *http.Request, ...interface{}) error { return nil }
+	anon := struct{ A []*http.Cookie }{}
+	for _, c := range []struct {
+		p    proto.Printer
+		x    interface{}
+		want string
+	}{
+		{proto.Printer{}, fn, "func(net/http.ResponseWriter, *net/http.Request, ...interface{}) error"},
+		{proto.Printer{Qualifier: proto.ShortQualifier}, fn, "func(http.ResponseWriter, *http.Request, ...interface{}) error"},
+		{proto.Printer{Mode: proto.VariadicSlice | proto.KeepInterface}, fn, "func(net/http.ResponseWriter, *net/http.Request, []interface {}) error"},
+		{proto.Printer{}, errors.New("x"), "error"},
+		{proto.Printer{Mode: proto.KeepError}, errors.New("x"), "*errors.errorString"},
+		{proto.Printer{}, anon, "struct { A []*net/http.Cookie }"},
+		{proto.Printer{Mode: proto.FoldStruct}, anon, "struct {...}"},
+		{proto.Printer{Qualifier: proto.ModuleQualifier("github.com/gohub/typeless")}, &proto.T{}, "*proto.T"},
+		{proto.Printer{Qualifier: proto.ModuleQualifier("github.com/gohub/typeless/proto")}, proto.T{}, "T"},
+		{proto.Printer{Qualifier: proto.ModuleQualifier("example.com/m")}, proto.T{}, "github.com/gohub/typeless/proto.T"},
+	} {
+		if got := c.p.Type(c.x); got != c.want {
+			T.Errorf("want: %s\n got: %s", c.want, got)
+		}
+	}
+}
+
+func TestPrinterDefault(T *testing.T) {
+	p := &proto.Printer{}
+	corpus := append(append([]interface{}{}, Builtins...), Funs...)
+	if got, want := fmt.Sprint(p.Types(corpus...)), fmt.Sprint(proto.Types(corpus...)); got != want {
+		T.Errorf("want: %s\n got: %s", want, got)
+	}
+	if got, want := p.Join(corpus...), proto.Join(corpus...); got != want {
+		T.Errorf("want: %s\n got: %s", want, got)
+	}
+}
+
+func TestVendorQualifier(T *testing.T) {
+	for path, want := range map[string]string{
+		"example.com/m/vendor/golang.org/x/net": "golang.org/x/net",
+		"vendor/golang.org/x/net/http2":         "golang.org/x/net/http2",
+		"net/http":                              "net/http",
+	} {
+		if got := proto.VendorQualifier(path); got != want {
+			T.Errorf("want: %s\n got: %s", want, got)
+		}
+	}
+}
+
+func TestStrictStruct(T *testing.T) {
+	p := &proto.Printer{Mode: proto.StrictStruct}
+	type local struct{ a int }
+	x := struct {
+		io.Reader
+		*http.Cookie
+		Name string `json:"name,omitempty"`
+		a    []local
+	}{}
+	want := `struct { io.Reader; *net/http.Cookie; Name string "json:\"name,omitempty\""; ` +
+		`github.com/gohub/typeless/proto_test.a []github.com/gohub/typeless/proto_test.local }`
+	s := p.Type(x)
+	if s != want {
+		T.Errorf("want: %s\n got: %s", want, s)
+	}
+	if got := proto.MustParse(s).String(); got != s {
+		T.Errorf("round trip want: %s\n got: %s", s, got)
+	}
+
+	// reflect.StructOf 不支持非首个嵌入字段带有方法
+	y := struct {
+		Name string `json:"name"`
+		a    []local
+	}{}
+	r := &proto.Registry{}
+	r.Register(reflect.TypeOf(local{}))
+	t, err := r.Lookup(p.Type(y))
+	if err != nil || t != reflect.TypeOf(y) {
+		T.Errorf("want: %v\n got: %v %v", reflect.TypeOf(y), t, err)
+	}
+
+	// 不同包中同名的非导出字段
+	other := reflect.StructOf([]reflect.StructField{{Name: "a", PkgPath: "example.com/other", Type: reflect.TypeOf(0)}})
+	mine := reflect.TypeOf(struct{ a int }{})
+	if proto.Type(other) != proto.Type(mine) || p.Type(other) == p.Type(mine) {
+		T.Errorf("bad strict struct %s %s", p.Type(other), p.Type(mine))
+	}
+}
+
+// 缓存的结果与直接生成的结果一致, 可以并发使用
+func TestTypeCache(T *testing.T) {
+	want := make([]string, len(benchValues))
+	for i, v := range benchValues {
+		want[i] = (&proto.Printer{}).Type(v)
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, got := range proto.Types(benchValues...) {
+				if got != want[i] {
+					T.Errorf("want: %s\n got: %s", want[i], got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := proto.Join(benchValues[:2]...); got != "int, string" {
+		T.Errorf("want: int, string\n got: %s", got)
+	}
+}
+
+// 类型参数与顶层类型采用相同的配置
+func TestTypeArgsMode(T *testing.T) {
+	v := List[struct {
+		A int `json:"a"`
+	}]{}
+	for _, mode := range []proto.Mode{0, proto.FoldStruct, proto.StrictStruct} {
+		p := &proto.Printer{Mode: mode}
+		want := "github.com/gohub/typeless/proto_test.List[" + p.Type(struct {
+			A int `json:"a"`
+		}{}) + "]"
+		if got := p.Type(v); got != want {
+			T.Errorf("mode %d want: %s\n got: %s", mode, want, got)
+		}
+	}
+}
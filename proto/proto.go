@@ -21,8 +21,15 @@ proto 是一个关于对象原型处理的工具.采用完整 PkgPath 对类型
 为了简便, 其他与 reflect 不同的地方
   * error 直接描述为 error, 而非 *errors.errorString
   * interface{} 直接描述为 interface{}, 而非 interface {}
+  * 匿名 interface 描述为按名称排序的方法集, 例如 `interface { Close() error; Read([]uint8) (int, error) }`
   * 匿名 struct ,直接返回完全字符串描述, 例如 `struct { A string; a string; B int }`
   * 特殊值 nil 直接描述为 nil, 而非 <nil>
+命名类型的描述规则
+  * 内置类型直接使用名称, 例如 int, error, unsafe.Pointer
+  * 其他命名类型描述为 PkgPath + "." + 名称, 与底层类型无关, 例如 net/http.Header, net/http.HandlerFunc
+  * 泛型实例的类型参数同样采用 proto 描述, 用 ", " 分隔, 例如 example.com/x.Map[string, example.com/y.Item]
+  * 函数内定义的类型与同名的包级类型描述相同, 无法区分
+以上是默认配置, Printer 可以改变这些行为, 以及包名的限定方式.
 */
 package proto
 
@@ -38,12 +45,8 @@ func Type(x interface{}) string {
 }
 
 // 返回多个 interface{} 的 proto []string
-func Types(in ...interface{}) (ss []string) {
-	ss = make([]string, len(in))
-	for i, v := range in {
-		ss[i] = prototype(v)
-	}
-	return
+func Types(in ...interface{}) []string {
+	return std.Types(in...)
 }
 
 // 获取 TypeOf
@@ -84,100 +87,9 @@ func ValueIndirect(i interface{}) (v reflect.Value) {
 	return
 }
 
+// 默认配置的 proto 描述
 func prototype(x interface{}) string {
-	if x == nil {
-		return "nil"
-	}
-	if _, ok := x.(error); ok {
-		return "error"
-	}
-
-	t, ok := x.(reflect.Type)
-	if !ok {
-		t = reflect.TypeOf(x)
-	}
-	k := t.Kind()
-	switch k {
-	case reflect.Array:
-		return "[" + strconv.Itoa(t.Len()) + "]" + prototype(t.Elem())
-	case reflect.Chan:
-		return t.ChanDir().String() + " " + prototype(t.Elem())
-	case reflect.Func:
-		s := "func("
-		max := t.NumIn() - 1
-		for i := 0; i <= max; i++ {
-			if i != max {
-				s += prototype(t.In(i)) + ", "
-			} else {
-				if t.IsVariadic() {
-					s += "..." + prototype(t.In(i).Elem())
-				} else {
-					s += prototype(t.In(i))
-				}
-			}
-		}
-		max = t.NumOut() - 1
-
-		if max > 0 {
-			s += ") ("
-		} else if max == 0 {
-			s += ") "
-		} else {
-			s += ")"
-		}
-
-		for i := 0; i <= max; i++ {
-			s += prototype(t.Out(i))
-			if i != max {
-				s += ", "
-			}
-		}
-		if max > 0 {
-			s += ")"
-		}
-		return s
-	case reflect.Map:
-		return "map[" + prototype(t.Key()) + "]" + prototype(t.Elem())
-	case reflect.Ptr:
-		return "*" + prototype(t.Elem())
-	case reflect.Slice:
-		return "[]" + prototype(t.Elem())
-	case reflect.Interface:
-		if t.Name() == "" {
-			return "interface{}"
-		}
-		if t.PkgPath() == "" {
-			return t.Name()
-		}
-
-		return t.PkgPath() + "." + t.Name()
-	case reflect.Uintptr, reflect.UnsafePointer:
-		return k.String()
-	case reflect.Struct:
-		p := t.PkgPath()
-		n := t.Name()
-		if p == "" {
-			if n == "" {
-				n = "struct { "
-				for i := 0; i < t.NumField(); i++ {
-					f := t.Field(i)
-					if i == 0 {
-						n += f.Name
-					} else {
-						n += "; " + f.Name
-					}
-					n += " " + prototype(f.Type)
-				}
-			}
-			return n + " }"
-		}
-		return p + "." + n
-	}
-	pp := t.PkgPath()
-	if pp == "" {
-		return t.String()
-	}
-	return t.PkgPath() + "." + t.String()
+	return std.Type(x)
 }
 
 // 判断是否是一个 reflect.Type
@@ -194,16 +106,7 @@ func IsValue(i interface{}) bool {
 
 // 把根据参数来生成 proto 描述,并用 ", " 连接
 func Join(in ...interface{}) string {
-	max := len(in) - 1
-	s := ""
-	for i, v := range in {
-		if i != max {
-			s += prototype(v) + ", "
-		} else {
-			s += prototype(v)
-		}
-	}
-	return s
+	return std.Join(in...)
 }
 
 // 如果 prefix 非空, 返回 "prefix, "+Join(in ...)
@@ -283,9 +186,12 @@ type ProtoType interface {
 	New(...interface{}) interface{}
 }
 
-// 类型描述
+// 类型描述, Name 是类型, 变量或者字段的名称, Type 是变量在代码中的类型写法,
+// Proto 是 proto 描述. 描述类型时 Type 为空.
 type T struct {
-	Name, Type, Proto string
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type,omitempty"`
+	Proto string `json:"proto"`
 }
 
 func (t *T) String() string {
@@ -301,36 +207,103 @@ func (t *T) String() string {
 	return "var " + t.Name + " " + t.Type
 }
 
-func (t *T) Code(name ...string) string {
-	if t.Type == "" {
-		return ""
+// 实例描述
+type Instance struct {
+	T
+	Value interface{} `json:"value"`
+}
+
+// 函数, 可变参数时 In 的最后一个是 slice 类型
+type Fn struct {
+	T
+	In       []T  `json:"in,omitempty"`
+	Out      []T  `json:"out,omitempty"`
+	Variadic bool `json:"variadic,omitempty"`
+}
+
+// 有名称时返回 func Name(In) Out 形式, 命名的函数类型返回 type Name func(In) Out 形式
+func (f *Fn) String() string {
+	if f.Name == "" {
+		return f.Proto
 	}
-	if len(name) != 0 {
-		return "var " + strings.Join(name, ", ") + " " + t.Type
+	if f.named() {
+		return "type " + f.Name + " func" + f.signature()
 	}
-	if t.Name == "" {
-		return ""
+	return "func " + f.Name + f.signature()
+}
+
+// 是否是命名的函数类型, 此时 Name 是类型名, 否则 Name 是函数或方法名.
+// 命名类型的 Proto 是类型名, 函数和方法的 Proto 是签名.
+func (f *Fn) named() bool {
+	return f.Name != "" && f.Proto != "" && !strings.HasPrefix(f.Proto, "func(")
+}
+
+// 返回 (In) Out 形式的签名
+func (f *Fn) signature() string {
+	s := "("
+	for i, t := range f.In {
+		if i != 0 {
+			s += ", "
+		}
+		if f.Variadic && i == len(f.In)-1 {
+			s += "..." + strings.TrimPrefix(t.Proto, "[]")
+		} else {
+			s += t.Proto
+		}
 	}
-	return "var " + t.Name + " " + t.Type
+	s += ")"
+	if len(f.Out) == 1 {
+		return s + " " + f.Out[0].Proto
+	}
+	if len(f.Out) > 1 {
+		s += " ("
+		for i, t := range f.Out {
+			if i != 0 {
+				s += ", "
+			}
+			s += t.Proto
+		}
+		s += ")"
+	}
+	return s
 }
 
-// 实例描述
-type Instance struct {
+// 接口
+type Interface struct {
 	T
-	Value interface{}
+	Methods map[string]Fn `json:"methods,omitempty"`
 }
 
-// 函数
-type Fn struct {
+func (i *Interface) String() string {
+	return i.Proto
+}
+
+// 结构体字段
+type Field struct {
 	T
-	In, Out []T
+	Tag      string `json:"tag,omitempty"`
+	Embedded bool   `json:"embedded,omitempty"`
+	Exported bool   `json:"exported,omitempty"`
 }
 
-// 接口
-type Interface map[string]ProtoType
+func (f *Field) String() string {
+	s := f.Proto
+	if !f.Embedded {
+		s = f.Name + " " + s
+	}
+	if f.Tag != "" {
+		s += " " + strconv.Quote(f.Tag)
+	}
+	return s
+}
 
 // 结构体
 type Struct struct {
-	Fields  map[string]T
-	Methods map[string]Fn
+	T
+	Fields  []Field       `json:"fields,omitempty"`
+	Methods map[string]Fn `json:"methods,omitempty"`
+}
+
+func (s *Struct) String() string {
+	return s.Proto
 }
@@ -1,11 +1,19 @@
 package proto_test
 
 import (
+	"bytes"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/gohub/typeless/proto"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 	"unsafe"
 )
 
@@ -36,27 +44,123 @@ func init() {
 	}
 }
 
-// 人工识别
-func TestBuiltin(T *testing.T) {
-	for _, k := range Builtins {
-		s := proto.Type(k)
-		if k != nil && s != reflect.TypeOf(k).String() {
-			fmt.Println(s, "\t\t", reflect.TypeOf(k))
-		}
-	}
-}
+var update = flag.Bool("update", false, "update testdata/*.golden")
 
-// 人工识别
-func TestFunc(T *testing.T) {
-	for _, k := range Funs {
-		if proto.Type(k) != reflect.TypeOf(k).String() {
-			fmt.Println(proto.Type(k), "\t\t", reflect.TypeOf(k).String())
-		}
+type (
+	IDs         []int
+	Names       map[string]IDs
+	HandlerFn   func(http.ResponseWriter, *http.Request) error
+	Events      chan<- IDs
+	Duration    time.Duration
+	List[T any] struct {
+		Items []T
+	}
+	Pair[K comparable, V any] struct {
+		Key K
+		Val V
 	}
-	fmt.Println(proto.Type(struct {
+)
+
+// 命名类型的标准描述
+var Named = []interface{}{
+	IDs{}, Names{}, HandlerFn(nil), Events(nil), Duration(0), []*IDs{},
+	reflect.Ptr, time.Second, http.Header{}, http.HandlerFunc(nil), template.HTML(""),
+	List[int]{}, List[User]{}, List[map[string]*User]{}, List[List[IDs]]{},
+	Pair[string, []*http.Request]{}, Pair[Events, func(IDs) error]{},
+	List[struct{ A IDs }]{}, List[chan<- http.Header]{}, List[interface{}]{},
+	struct{}{}, List[struct{}]{},
+	(*interface {
+		Read([]byte) (int, error)
+		Close() error
+	})(nil),
+	(*interface {
+		m(IDs)
+		M() (*interface{ M() }, error)
+	})(nil),
+	List[interface{ String() string }]{},
+	struct {
 		ddd proto.ProtoType
 		A   string
 		a   string
 		B   int
-	}{}), "\t\t", struct{ A string }{A: "struct"})
+	}{},
+	struct {
+		F func() `json:"f"`
+		G int
+	}{},
+	List[struct {
+		A int `json:"a"`
+	}]{},
+	List[struct {
+		*http.Cookie
+		a int
+	}]{},
+	List[func(...int) interface{}]{},
+	List[interface{ m() }]{},
+}
+
+// 比较 testdata/canonical.golden, 使用 -update 参数更新
+func TestGolden(T *testing.T) {
+	var b bytes.Buffer
+	strict := &proto.Printer{Mode: proto.StrictStruct}
+	keep := &proto.Printer{Mode: proto.KeepInterface}
+	for _, k := range append(append(append([]interface{}{}, Builtins...), Funs...), Named...) {
+		s := proto.Type(k)
+		for _, s := range []string{s, strict.Type(k), keep.Type(k)} {
+			if x, err := proto.Parse(s); err != nil {
+				T.Errorf("%s: %v", s, err)
+			} else if x.String() != s {
+				T.Errorf("round trip want: %s\n got: %s", s, x.String())
+			}
+		}
+		fmt.Fprintf(&b, "%v\t%s\n", reflect.TypeOf(k), s)
+	}
+	golden := filepath.Join("testdata", "canonical.golden")
+	if *update {
+		if err := os.WriteFile(golden, b.Bytes(), 0644); err != nil {
+			T.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		T.Fatal(err)
+	}
+	wants, gots := strings.Split(string(want), "\n"), strings.Split(b.String(), "\n")
+	if len(gots) != len(wants) {
+		T.Errorf("want %d lines, got %d", len(wants), len(gots))
+	}
+	for i, got := range gots {
+		if i >= len(wants) {
+			T.Errorf("line %d want: <EOF>\n got: %s", i+1, got)
+		} else if got != wants[i] {
+			T.Errorf("line %d want: %s\n got: %s", i+1, wants[i], got)
+		}
+	}
+}
+
+// auto.Group 中常见的参数
+var benchValues = []interface{}{
+	1, "string", &User{}, http.HandlerFunc(nil), map[string][]int{},
+	func(int, ...string) error { return nil }, List[*User]{},
+}
+
+func BenchmarkType(B *testing.B) {
+	B.ReportAllocs()
+	for i := 0; i < B.N; i++ {
+		proto.Type(benchValues[i%len(benchValues)])
+	}
+}
+
+func BenchmarkTypes(B *testing.B) {
+	B.ReportAllocs()
+	for i := 0; i < B.N; i++ {
+		proto.Types(benchValues...)
+	}
+}
+
+func BenchmarkJoin(B *testing.B) {
+	B.ReportAllocs()
+	for i := 0; i < B.N; i++ {
+		proto.Join(benchValues...)
+	}
 }
@@ -0,0 +1,368 @@
+package proto
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 字段编号, 消息的 proto 描述 -> 字段名 -> 编号.
+// Protobuf 为新字段分配的编号写入其中, 以 JSON 等形式持久化后可以保持编号稳定,
+// 删除的字段仍然保留, 其编号不会被重用.
+type FieldNumbers map[string]map[string]int
+
+// 字段编号的范围, 19000 到 19999 由 protobuf 保留
+const (
+	maxFieldNumber      = 1<<29 - 1
+	reservedFieldNumber = 19000
+	reservedFieldEnd    = 19999
+)
+
+// 生成 proto3 定义, pkg 是 package 名称, 为空时省略. 参数可以是 *Struct, 值或者 reflect.Type.
+// 规则如下
+//   - 每个命名 struct 生成一个 message, 引用的命名 struct 一并生成, 名称重复时使用完整的 proto 描述
+//   - 字段编号取自 protobuf tag, 例如 `protobuf:"bytes,3,opt,name=id"` 或 `protobuf:"3"`,
+//     其次是 nums, 都没有时分配未使用的最小编号并写入 nums. nums 可以为 nil.
+//     tag 与 nums 中的编号不同是错误, 以免旧编号被静默释放.
+//   - 字段名取自 protobuf tag 的 name=, 否则是字段名的 snake_case 形式.
+//     字段名重复是错误, 例如 UserID 和 UserId 都是 user_id.
+//     非导出字段和 `protobuf:"-"` 被忽略, 嵌入字段是普通字段.
+//   - 整数, 浮点数, bool, string 对应标量类型, []byte 为 bytes, 指向标量的指针为 optional,
+//     slice 和 array 为 repeated, map 为 map<K, V>,
+//     time.Time 和 time.Duration 为 google.protobuf.Timestamp 和 google.protobuf.Duration
+//
+// chan, func, interface, complex, 匿名 struct 等无法表示的类型返回包含字段位置的错误.
+func Protobuf(pkg string, nums FieldNumbers, xs ...interface{}) (string, error) {
+	if nums == nil {
+		nums = FieldNumbers{}
+	}
+	// 第一遍收集全部 message 以确定名称, 第二遍生成
+	e := &pbExporter{nums: nums}
+	if _, err := e.source(pkg, xs); err != nil {
+		return "", err
+	}
+	e.names = pbNames(e.msgs)
+	return e.source(pkg, xs)
+}
+
+type pbExporter struct {
+	nums    FieldNumbers
+	names   map[string]string // proto 描述 -> message 名称
+	msgs    map[string]string // proto 描述 -> message 定义, 生成中的为空
+	imports map[string]bool
+}
+
+// message 的名称, 类型名重复时使用完整的 proto 描述
+func pbNames(msgs map[string]string) map[string]string {
+	names := map[string]string{}
+	count := map[string]int{}
+	for s := range msgs {
+		_, name := tsName(s)
+		names[s] = name
+		count[name]++
+	}
+	for s, name := range names {
+		if count[name] > 1 {
+			names[s] = tsIdent(s)
+		}
+	}
+	return names
+}
+
+func (e *pbExporter) name(s string) string {
+	if name, ok := e.names[s]; ok {
+		return name
+	}
+	_, name := tsName(s)
+	return name
+}
+
+func (e *pbExporter) source(pkg string, xs []interface{}) (string, error) {
+	e.msgs = map[string]string{}
+	e.imports = map[string]bool{}
+	for _, x := range xs {
+		p, r := newResolver(x)
+		s, ok := p.(*Struct)
+		if !ok || s.Name == "" {
+			return "", toExportFailed("protobuf needs a named struct: ", protoOf(p))
+		}
+		if err := e.message(r, s.Proto, s.Fields); err != nil {
+			return "", err
+		}
+	}
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n")
+	if pkg != "" {
+		b.WriteString("\npackage " + pkg + ";\n")
+	}
+	if len(e.imports) != 0 {
+		b.WriteByte('\n')
+		for _, path := range sortedKeys(e.imports) {
+			b.WriteString("import " + strconv.Quote(path) + ";\n")
+		}
+	}
+	names := make([]string, 0, len(e.msgs))
+	for s := range e.msgs {
+		names = append(names, s)
+	}
+	sort.Slice(names, func(i, j int) bool { return e.name(names[i]) < e.name(names[j]) })
+	for _, s := range names {
+		b.WriteString("\n" + e.msgs[s])
+	}
+	return b.String(), nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 一个 message 字段
+type pbField struct {
+	Field
+	name   string
+	number int
+}
+
+// 生成命名 struct s 的 message
+func (e *pbExporter) message(r *resolver, s string, fields []Field) error {
+	if _, ok := e.msgs[s]; ok {
+		return nil
+	}
+	e.msgs[s] = ""
+	_, typName := tsName(s)
+	var fs []pbField
+	names := map[string]string{} // message 字段名 -> struct 字段名
+	for _, f := range fields {
+		tag := reflect.StructTag(f.Tag).Get("protobuf")
+		if tag == "-" || !f.Exported {
+			continue
+		}
+		pf := pbField{Field: f, name: snakeCase(f.Name)}
+		for _, o := range strings.Split(tag, ",") {
+			if strings.HasPrefix(o, "name=") {
+				pf.name = o[len("name="):]
+			} else if n, err := strconv.Atoi(o); err == nil && pf.number == 0 {
+				pf.number = n
+			}
+		}
+		if name, ok := names[pf.name]; ok {
+			return toExportFailed(typName, ".", f.Name, ": field name ", pf.name, " is used by ", name)
+		}
+		names[pf.name] = f.Name
+		fs = append(fs, pf)
+	}
+	if err := e.number(typName, s, fs); err != nil {
+		return err
+	}
+	src := "message " + e.name(s) + " {\n"
+	for _, f := range fs {
+		typ, err := e.fieldType(r, typName+"."+f.Name, f.Proto)
+		if err != nil {
+			return err
+		}
+		src += "  " + typ + " " + f.name + " = " + strconv.Itoa(f.number) + ";\n"
+	}
+	e.msgs[s] = src + "}\n"
+	return nil
+}
+
+// 分配字段编号, 并检查冲突
+func (e *pbExporter) number(path, s string, fs []pbField) error {
+	m := e.nums[s]
+	if m == nil {
+		m = map[string]int{}
+		e.nums[s] = m
+	}
+	used := map[int]string{}
+	for name, n := range m {
+		used[n] = name
+	}
+	for i := range fs {
+		f := &fs[i]
+		if old := m[f.Name]; f.number == 0 {
+			f.number = old
+		} else if old != 0 && old != f.number {
+			return toExportFailed(path, ".", f.Name, ": field number ", f.number, " does not match saved number ", old)
+		}
+		if f.number == 0 {
+			continue
+		}
+		if f.number < 0 || f.number > maxFieldNumber ||
+			f.number >= reservedFieldNumber && f.number <= reservedFieldEnd {
+			return toExportFailed(path, ".", f.Name, ": invalid field number ", f.number)
+		}
+		if name, ok := used[f.number]; ok && name != f.Name {
+			return toExportFailed(path, ".", f.Name, ": field number ", f.number, " is used by ", name)
+		}
+		used[f.number] = f.Name
+		m[f.Name] = f.number
+	}
+	next := 1
+	for i := range fs {
+		f := &fs[i]
+		if f.number != 0 {
+			continue
+		}
+		for used[next] != "" || next >= reservedFieldNumber && next <= reservedFieldEnd {
+			next++
+		}
+		f.number = next
+		used[next] = f.Name
+		m[f.Name] = next
+	}
+	return nil
+}
+
+// 字段类型, 包含 repeated, optional
+func (e *pbExporter) fieldType(r *resolver, path, s string) (string, error) {
+	x, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	switch x.Kind {
+	case KindSlice, KindArray:
+		if x.Kind == KindSlice && isByte(x.Elem) {
+			return "bytes", nil
+		}
+		elem, err := e.elemType(r, path, x.Elem)
+		if err != nil {
+			return "", err
+		}
+		return "repeated " + elem, nil
+	case KindMap:
+		key, err := e.expr(r, path, x.Key)
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case "int32", "int64", "uint32", "uint64", "bool", "string":
+		default:
+			return "", toExportFailed(path, ": unsupported map key ", x.Key)
+		}
+		elem, err := e.elemType(r, path, x.Elem)
+		if err != nil {
+			return "", err
+		}
+		return "map<" + key + ", " + elem + ">", nil
+	case KindPtr:
+		elem, err := e.expr(r, path, x.Elem)
+		if err != nil {
+			return "", err
+		}
+		if isScalar(elem) {
+			return "optional " + elem, nil
+		}
+		return elem, nil
+	}
+	return e.expr(r, path, x)
+}
+
+// repeated 和 map 的元素不能是 repeated 或 map
+func (e *pbExporter) elemType(r *resolver, path string, x *Expr) (string, error) {
+	if x.Kind == KindPtr {
+		x = x.Elem
+	}
+	typ, err := e.expr(r, path, x)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(typ, "repeated ") || strings.HasPrefix(typ, "map<") {
+		return "", toExportFailed(path, ": unsupported nested ", x)
+	}
+	return typ, nil
+}
+
+func isByte(x *Expr) bool {
+	return x.Kind == KindIdent && x.PkgPath == "" && x.Name == "uint8"
+}
+
+func isScalar(typ string) bool {
+	switch typ {
+	case "double", "float", "int32", "int64", "uint32", "uint64", "bool", "string", "bytes":
+		return true
+	}
+	return false
+}
+
+// 单个值的类型
+func (e *pbExporter) expr(r *resolver, path string, x *Expr) (string, error) {
+	if x.Kind != KindIdent {
+		if x.Kind == KindSlice && isByte(x.Elem) {
+			return "bytes", nil
+		}
+		if x.Kind == KindSlice || x.Kind == KindArray || x.Kind == KindMap {
+			return e.fieldType(r, path, x.String())
+		}
+		return "", toExportFailed(path, ": unsupported type ", x)
+	}
+	if x.PkgPath == "" {
+		switch x.Name {
+		case "bool", "string", "int32", "int64", "uint32", "uint64":
+			return x.Name, nil
+		case "int":
+			return "int64", nil
+		case "int8", "int16":
+			return "int32", nil
+		case "uint", "uintptr":
+			return "uint64", nil
+		case "uint8", "uint16":
+			return "uint32", nil
+		case "float32":
+			return "float", nil
+		case "float64":
+			return "double", nil
+		}
+		return "", toExportFailed(path, ": unsupported type ", x)
+	}
+	s := x.String()
+	t, err := r.lookup(s)
+	if err != nil {
+		return "", toExportFailed(path, ": ", err)
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		e.imports["google/protobuf/timestamp.proto"] = true
+		return "google.protobuf.Timestamp", nil
+	case reflect.TypeOf(time.Duration(0)):
+		e.imports["google/protobuf/duration.proto"] = true
+		return "google.protobuf.Duration", nil
+	}
+	if t.Kind() == reflect.Struct {
+		if err = e.message(r, s, structFields(t)); err != nil {
+			return "", err
+		}
+		return e.name(s), nil
+	}
+	u := underlying(t)
+	if u == "" {
+		return "", toExportFailed(path, ": unsupported type ", s)
+	}
+	return e.fieldType(r, path, u)
+}
+
+// 字段名的 snake_case 形式, 例如 UserID 为 user_id, HTTPServer 为 http_server
+func snakeCase(name string) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, c := range rs {
+		if unicode.IsUpper(c) {
+			// 缩写之后的单词另起, 但复数的缩写 IDs 为 ids
+			if i != 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]) ||
+				unicode.IsUpper(rs[i-1]) && i+1 < len(rs) && unicode.IsLower(rs[i+1]) &&
+					!(rs[i+1] == 's' && i+2 == len(rs))) {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
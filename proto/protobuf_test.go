@@ -0,0 +1,105 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Invoice struct {
+	ID       int64    `protobuf:"varint,5,opt,name=id"`
+	Customer *Address `protobuf:"3"`
+	Lines    []Line
+	Labels   map[string]string
+	Paid     *bool
+	Issued   time.Time
+	Due      time.Duration
+	Raw      []byte
+	Skip     string `protobuf:"-"`
+	Base
+	note string
+}
+
+type Line struct {
+	SKU    string
+	Amount float64
+	IDs    IDs
+}
+
+func TestProtobuf(T *testing.T) {
+	nums := proto.FieldNumbers{
+		proto.Type(Line{}): {"Removed": 1},
+	}
+	src, err := proto.Protobuf("shop", nums, Invoice{})
+	if err != nil {
+		T.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "invoice.proto")
+	if *update {
+		if err = os.WriteFile(golden, []byte(src), 0644); err != nil {
+			T.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		T.Fatal(err)
+	}
+	if src != string(want) {
+		T.Errorf("want:\n%s\n got:\n%s", want, src)
+	}
+	// 编号稳定
+	saved := proto.FieldNumbers{}
+	for k, m := range nums {
+		saved[k] = map[string]int{}
+		for f, n := range m {
+			saved[k][f] = n
+		}
+	}
+	again, err := proto.Protobuf("shop", saved, Invoice{})
+	if err != nil || again != src || !reflect.DeepEqual(saved, nums) {
+		T.Errorf("want stable numbers, got %v\n%s", err, again)
+	}
+}
+
+func TestProtobufError(T *testing.T) {
+	type withChan struct{ Events chan int }
+	type withFunc struct{ OnDone func() }
+	type nested struct{ Grid [][]int }
+	type dup struct {
+		A int `protobuf:"1"`
+		B int `protobuf:"1"`
+	}
+	type snake struct {
+		UserID int
+		UserId int
+	}
+	for _, c := range []struct {
+		x    interface{}
+		want string
+	}{
+		{withChan{}, "proto export failed: withChan.Events: unsupported type chan int"},
+		{withFunc{}, "proto export failed: withFunc.OnDone: unsupported type func()"},
+		{nested{}, "proto export failed: nested.Grid: unsupported nested []int"},
+		{dup{}, "proto export failed: dup.B: field number 1 is used by A"},
+		{snake{}, "proto export failed: snake.UserId: field name user_id is used by UserID"},
+		{1, "proto export failed: protobuf needs a named struct: int"},
+	} {
+		_, err := proto.Protobuf("", nil, c.x)
+		if err == nil || err.Error() != c.want {
+			T.Errorf("want %q, got %v", c.want, err)
+		}
+	}
+
+	// tag 改变了已保存的编号
+	type moved struct {
+		A int `protobuf:"2"`
+	}
+	nums := proto.FieldNumbers{proto.Type(moved{}): {"A": 1}}
+	want := "proto export failed: moved.A: field number 2 does not match saved number 1"
+	if _, err := proto.Protobuf("", nums, moved{}); err == nil || err.Error() != want {
+		T.Errorf("want %q, got %v", want, err)
+	}
+}
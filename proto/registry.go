@@ -0,0 +1,215 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+func toNotRegistered(s ...interface{}) error {
+	return errors.New("proto not registered: " + fmt.Sprint(s...))
+}
+func toInvalidType(s ...interface{}) error {
+	return errors.New("proto invalid type: " + fmt.Sprint(s...))
+}
+
+// 内置类型, 所有 Registry 都可以查找到
+var builtins = map[string]reflect.Type{}
+
+// 内置类型的指纹, Hash -> proto 描述
+var builtinHashes = map[Hash]string{}
+
+func init() {
+	var (
+		e error
+		p unsafe.Pointer
+	)
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(false), reflect.TypeOf(""),
+		reflect.TypeOf(int(0)), reflect.TypeOf(int8(0)), reflect.TypeOf(int16(0)),
+		reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)),
+		reflect.TypeOf(uint(0)), reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)),
+		reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)), reflect.TypeOf(uintptr(0)),
+		reflect.TypeOf(float32(0)), reflect.TypeOf(float64(0)),
+		reflect.TypeOf(complex64(0)), reflect.TypeOf(complex128(0)),
+		reflect.TypeOf(p), reflect.TypeOf(&e).Elem(),
+	} {
+		s := prototype(t)
+		builtins[s] = t
+		builtinHashes[FingerprintString(s)] = s
+	}
+	builtins["byte"] = builtins["uint8"]
+	builtins["rune"] = builtins["int32"]
+}
+
+var emptyInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// 默认的类型注册表
+var Default = &Registry{}
+
+// 在默认注册表中注册类型
+func Register(types ...interface{}) {
+	Default.Register(types...)
+}
+
+// 在默认注册表中查找 proto 描述对应的 reflect.Type
+func Lookup(s string) (reflect.Type, error) {
+	return Default.Lookup(s)
+}
+
+// Registry 保存 proto 描述到 reflect.Type 的映射.
+// 只需要注册命名类型, 复合类型由 Lookup 通过 reflect.SliceOf 等方法生成.
+// 内置类型已经预先注册. Registry 可以并发使用.
+type Registry struct {
+	lock   sync.RWMutex
+	m      map[string]reflect.Type
+	hashes map[Hash]string // 指纹到 m 中 proto 描述的映射
+	// Lookup 生成的复合类型, 与注册的类型分开保存, 以免影响之后的 Register
+	built       map[string]reflect.Type
+	builtHashes map[Hash]reflect.Type
+}
+
+// 注册类型, 参数可以是 reflect.Type 或者值, 值以其动态类型注册.
+// 接口类型需要以 reflect.Type 形式注册, 例如
+//
+//	TypeIndirect((*io.Reader)(nil))
+//
+// 同一个 proto 描述注册不同的类型会直接抛出 panic.
+func (r *Registry) Register(types ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.init()
+	for _, x := range types {
+		if x == nil {
+			panic("proto register nil")
+		}
+		t := TypeOf(x)
+		key := prototype(t)
+		if old, ok := r.m[key]; ok && old != t {
+			panic("proto repeated: " + key)
+		}
+		r.m[key] = t
+		r.hashes[FingerprintString(key)] = key
+	}
+}
+
+func (r *Registry) init() {
+	if r.m == nil {
+		r.m = map[string]reflect.Type{}
+		r.hashes = map[Hash]string{}
+		r.built = map[string]reflect.Type{}
+		r.builtHashes = map[Hash]reflect.Type{}
+	}
+}
+
+// 查找 proto 描述对应的 reflect.Type.
+// 复合类型, 比如 []*pkg.T, map[string]pkg.T, func(int) error, chan<- pkg.T,
+// 由已注册的类型生成.
+func (r *Registry) Lookup(s string) (reflect.Type, error) {
+	if t, ok := r.lookup(s); ok {
+		return t, nil
+	}
+	x, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.build(x)
+	if err != nil {
+		return nil, err
+	}
+	// 缓存生成的复合类型, 指纹按照规范的 proto 描述计算
+	r.lock.Lock()
+	r.init()
+	r.built[s] = t
+	r.builtHashes[FingerprintString(prototype(t))] = t
+	r.lock.Unlock()
+	return t, nil
+}
+
+func (r *Registry) lookup(s string) (reflect.Type, bool) {
+	if t, ok := builtins[s]; ok {
+		return t, true
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if t, ok := r.m[s]; ok {
+		return t, true
+	}
+	t, ok := r.built[s]
+	return t, ok
+}
+
+// 由 Expr 生成 reflect.Type, reflect 的 panic 转换为 error
+func (r *Registry) build(x *Expr) (t reflect.Type, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			t, err = nil, toInvalidType(x, ": ", e)
+		}
+	}()
+	var k, e reflect.Type
+	switch x.Kind {
+	case KindIdent:
+		if t, ok := r.lookup(x.String()); ok {
+			return t, nil
+		}
+		return nil, toNotRegistered(x)
+	case KindInterface:
+		if len(x.Methods) != 0 {
+			return nil, toInvalidType("reflect can not build interface ", x)
+		}
+		return emptyInterface, nil
+	case KindArray, KindSlice, KindPtr, KindChan:
+		if e, err = r.build(x.Elem); err != nil {
+			return
+		}
+		switch x.Kind {
+		case KindArray:
+			return reflect.ArrayOf(x.Len, e), nil
+		case KindSlice:
+			return reflect.SliceOf(e), nil
+		case KindPtr:
+			return reflect.PointerTo(e), nil
+		}
+		return reflect.ChanOf(x.Dir, e), nil
+	case KindMap:
+		if k, err = r.build(x.Key); err != nil {
+			return
+		}
+		if e, err = r.build(x.Elem); err != nil {
+			return
+		}
+		return reflect.MapOf(k, e), nil
+	case KindFunc:
+		in := make([]reflect.Type, len(x.In))
+		out := make([]reflect.Type, len(x.Out))
+		for i, a := range x.In {
+			if in[i], err = r.build(a); err != nil {
+				return
+			}
+		}
+		if x.Variadic {
+			in[len(in)-1] = reflect.SliceOf(in[len(in)-1])
+		}
+		for i, a := range x.Out {
+			if out[i], err = r.build(a); err != nil {
+				return
+			}
+		}
+		return reflect.FuncOf(in, out, x.Variadic), nil
+	case KindStruct:
+		fs := make([]reflect.StructField, len(x.Fields))
+		for i, f := range x.Fields {
+			fs[i] = reflect.StructField{
+				Name: f.Name, PkgPath: f.PkgPath, Tag: reflect.StructTag(f.Tag), Anonymous: f.Embedded,
+			}
+			if fs[i].Type, err = r.build(f.Type); err != nil {
+				return
+			}
+		}
+		return reflect.StructOf(fs), nil
+	}
+	return nil, toInvalidType(strconv.Quote(x.String()))
+}
@@ -0,0 +1,92 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLookup(T *testing.T) {
+	r := &proto.Registry{}
+	r.Register(http.Cookie{}, http.Request{}, proto.TypeIndirect((*http.ResponseWriter)(nil)))
+
+	for _, x := range []interface{}{
+		http.Cookie{}, []*http.Request{}, map[string]http.Cookie{}, [2]string{},
+		func(int) error { return nil }, make(chan<- *http.Request), make(<-chan http.Cookie),
+		func(http.ResponseWriter, *http.Request) {}, func(string, ...interface{}) (int, error) { return 0, nil },
+		struct {
+			A string
+			B []byte
+		}{},
+		uintptr(0), complex64(0),
+	} {
+		s := proto.Type(x)
+		t, err := r.Lookup(s)
+		if err != nil {
+			T.Errorf("%s: %v", s, err)
+			continue
+		}
+		if t != reflect.TypeOf(x) {
+			T.Errorf("want: %v\n got: %v", reflect.TypeOf(x), t)
+		}
+	}
+}
+
+func TestLookupError(T *testing.T) {
+	r := &proto.Registry{}
+	for _, s := range []string{
+		"net/http.Cookie", "map[[]int]string", "struct { a int }", "nil", "func(",
+	} {
+		if _, err := r.Lookup(s); err == nil {
+			T.Errorf("want an error for %q", s)
+		}
+	}
+}
+
+func TestRegisterRepeated(T *testing.T) {
+	tagged := reflect.TypeOf(struct {
+		A int `json:"a"`
+	}{})
+	for _, c := range []struct {
+		name  string
+		panic bool
+		fn    func(r *proto.Registry)
+	}{
+		{"same type", false, func(r *proto.Registry) {
+			r.Register(http.Cookie{})
+			r.Register(http.Cookie{})
+		}},
+		{"same call", true, func(r *proto.Registry) {
+			r.Register(reflect.TypeOf(struct{ A int }{}), tagged)
+		}},
+		{"later call", true, func(r *proto.Registry) {
+			r.Register(reflect.TypeOf(struct{ A int }{}))
+			r.Register(tagged)
+		}},
+		// Lookup 生成的复合类型不是注册的类型
+		{"after lookup", false, func(r *proto.Registry) {
+			if _, err := r.Lookup("struct { A int }"); err != nil {
+				T.Fatal(err)
+			}
+			r.Register(tagged)
+		}},
+	} {
+		func() {
+			defer func() {
+				if got := recover() != nil; got != c.panic {
+					T.Errorf("%s: want panic %v, got %v", c.name, c.panic, got)
+				}
+			}()
+			c.fn(&proto.Registry{})
+		}()
+	}
+
+	// 注册的类型优先于 Lookup 生成的类型
+	r := &proto.Registry{}
+	r.Lookup("struct { A int }")
+	r.Register(tagged)
+	if t, err := r.Lookup("struct { A int }"); err != nil || t != tagged {
+		T.Errorf("want %v, got %v %v", tagged, t, err)
+	}
+}
@@ -0,0 +1,136 @@
+package static
+
+import (
+	"github.com/gohub/typeless/proto"
+	"go/types"
+	"sort"
+)
+
+// 返回 go/types 类型的原型描述, 与 proto.Describe 一致.
+//
+//	func      返回 *proto.Fn
+//	interface 返回 *proto.Interface
+//	struct    返回 *proto.Struct, 指向 struct 的指针也返回 *proto.Struct
+//	其他      返回 *proto.T
+//
+// 字段和参数的 T.Type 是以包名限定的源代码写法.
+func Describe(t types.Type) proto.ProtoType {
+	if t == nil {
+		return &proto.T{Proto: "nil"}
+	}
+	t = types.Unalias(t)
+	if b, ok := t.(*types.Basic); ok && b.Kind() == types.UntypedNil {
+		return &proto.T{Proto: "nil"}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Signature:
+		fn := describeFunc(u)
+		fn.T = proto.T{Name: name(t), Proto: Type(t)}
+		return &fn
+	case *types.Interface:
+		return &proto.Interface{T: proto.T{Name: name(t), Proto: Type(t)}, Methods: methods(t)}
+	case *types.Pointer:
+		if _, ok := u.Elem().Underlying().(*types.Struct); ok {
+			return describeStruct(types.Unalias(u.Elem()))
+		}
+	case *types.Struct:
+		return describeStruct(t)
+	}
+	return &proto.T{Name: name(t), Proto: Type(t)}
+}
+
+// 命名类型的名称, 与 reflect.Type.Name 一致
+func name(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Basic:
+		if t.Kind() == types.UnsafePointer {
+			return "Pointer"
+		}
+		return basicName(t)
+	case *types.Named:
+		return typeName(printer{&proto.Printer{}}, t)
+	}
+	return ""
+}
+
+// 以包名限定的源代码写法
+func code(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string { return p.Name() })
+}
+
+func describeT(name string, t types.Type) proto.T {
+	return proto.T{Name: name, Type: code(t), Proto: Type(t)}
+}
+
+// 不包含接收者的函数描述, Fn.T 由调用者设置
+func describeFunc(sig *types.Signature) (fn proto.Fn) {
+	params, results := sig.Params(), sig.Results()
+	fn.In = make([]proto.T, params.Len())
+	fn.Out = make([]proto.T, results.Len())
+	for i := range fn.In {
+		fn.In[i] = describeT("", params.At(i).Type())
+	}
+	for i := range fn.Out {
+		fn.Out[i] = describeT("", results.At(i).Type())
+	}
+	fn.Variadic = sig.Variadic()
+	return
+}
+
+func describeStruct(t types.Type) *proto.Struct {
+	st := t.Underlying().(*types.Struct)
+	s := &proto.Struct{T: proto.T{Name: name(t), Proto: Type(t)}, Methods: methods(t)}
+	s.Fields = make([]proto.Field, st.NumFields())
+	for i := range s.Fields {
+		f := st.Field(i)
+		s.Fields[i] = proto.Field{
+			T:        describeT(f.Name(), f.Type()),
+			Tag:      st.Tag(i),
+			Embedded: f.Embedded(),
+			Exported: f.Exported(),
+		}
+	}
+	return s
+}
+
+// 返回 t 的方法集, 按名称排序, 与 proto.Methods 一致.
+// 非指针类型也包含指针接收者的方法. Fn.Proto 不包含接收者.
+func Methods(t types.Type) []proto.Fn {
+	if t == nil {
+		return nil
+	}
+	m := methods(types.Unalias(t))
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fns := make([]proto.Fn, len(names))
+	for i, name := range names {
+		fns[i] = m[name]
+	}
+	return fns
+}
+
+// 返回 t 的方法集. 与 reflect 一致, 接口包含非导出方法, 其他类型只有导出方法.
+func methods(t types.Type) map[string]proto.Fn {
+	m := map[string]proto.Fn{}
+	_, isPtr := t.(*types.Pointer)
+	_, isInterface := t.Underlying().(*types.Interface)
+	if !isPtr && !isInterface {
+		t = types.NewPointer(t)
+	}
+	ms := types.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		sel := ms.At(i)
+		obj := sel.Obj()
+		if !isInterface && !obj.Exported() {
+			continue
+		}
+		sig := sel.Type().(*types.Signature)
+		fn := describeFunc(sig)
+		fn.T = proto.T{Name: obj.Name(), Proto: Type(sig)}
+		m[obj.Name()] = fn
+	}
+	return m
+}
@@ -0,0 +1,211 @@
+/*
+static 由 go/types 生成与 proto 一致的描述, 不需要加载和运行代码.
+lint, 代码生成等静态工具计算出的描述, 与运行时 proto.Type 的结果相同.
+
+与运行时的区别
+  - 静态类型是 interface 时, 运行时描述的是动态类型, 两者不能比较
+  - 无类型常量采用默认类型, 例如 1 描述为 int, 'a' 描述为 int32
+  - T.Type 是源代码中的写法, 例如 []byte, reflect 为 []uint8
+*/
+package static
+
+import (
+	"github.com/gohub/typeless/proto"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 返回 go/types 类型的 proto 描述, 与 proto.Type 的结果一致
+func Type(t types.Type) string {
+	return Print(&proto.Printer{}, t)
+}
+
+// 按照 Printer 的配置返回 proto 描述, 结果与 Printer.Type 一致. KeepError 没有作用.
+func Print(p *proto.Printer, t types.Type) string {
+	return printer{p}.typeString(t)
+}
+
+type printer struct {
+	*proto.Printer
+}
+
+func (p printer) qualify(pkgPath, name string) string {
+	if pkgPath == "" {
+		return name
+	}
+	if p.Qualifier != nil {
+		pkgPath = p.Qualifier(pkgPath)
+		if pkgPath == "" {
+			return name
+		}
+	}
+	return pkgPath + "." + name
+}
+
+// 与 reflect 一致的基础类型名称, 无类型常量采用默认类型
+func basicName(t *types.Basic) string {
+	switch t.Kind() {
+	case types.UntypedBool:
+		return "bool"
+	case types.UntypedInt:
+		return "int"
+	case types.UntypedRune:
+		return "int32"
+	case types.UntypedFloat:
+		return "float64"
+	case types.UntypedComplex:
+		return "complex128"
+	case types.UntypedString:
+		return "string"
+	case types.UntypedNil:
+		return "nil"
+	case types.UnsafePointer:
+		return "unsafe.Pointer"
+	}
+	// byte, rune 是别名, 以 Kind 取得 uint8, int32
+	return types.Typ[t.Kind()].Name()
+}
+
+func (p printer) typeString(t types.Type) string {
+	switch t := types.Unalias(t).(type) {
+	case *types.Basic:
+		return basicName(t)
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() == nil {
+			// error, comparable
+			return obj.Name()
+		}
+		return p.qualify(obj.Pkg().Path(), typeName(p, t))
+	case *types.TypeParam:
+		return t.Obj().Name()
+	case *types.Array:
+		return "[" + strconv.FormatInt(t.Len(), 10) + "]" + p.typeString(t.Elem())
+	case *types.Slice:
+		return "[]" + p.typeString(t.Elem())
+	case *types.Pointer:
+		return "*" + p.typeString(t.Elem())
+	case *types.Map:
+		return "map[" + p.typeString(t.Key()) + "]" + p.typeString(t.Elem())
+	case *types.Chan:
+		switch t.Dir() {
+		case types.SendOnly:
+			return "chan<- " + p.typeString(t.Elem())
+		case types.RecvOnly:
+			return "<-chan " + p.typeString(t.Elem())
+		}
+		return "chan " + p.typeString(t.Elem())
+	case *types.Signature:
+		return "func" + p.signature(t)
+	case *types.Struct:
+		return p.structString(t)
+	case *types.Interface:
+		return p.interfaceString(t)
+	case *types.Tuple:
+		ss := make([]string, t.Len())
+		for i := range ss {
+			ss[i] = p.typeString(t.At(i).Type())
+		}
+		return strings.Join(ss, ", ")
+	}
+	return t.String()
+}
+
+// 命名类型的名称, 泛型实例的类型参数采用 p 的 proto 描述
+func typeName(p printer, t *types.Named) string {
+	name := t.Obj().Name()
+	args := t.TypeArgs()
+	if args.Len() == 0 {
+		return name
+	}
+	ss := make([]string, args.Len())
+	for i := range ss {
+		ss[i] = p.typeString(args.At(i))
+	}
+	return name + "[" + strings.Join(ss, ", ") + "]"
+}
+
+// 不包含 func 的签名, 忽略接收者
+func (p printer) signature(t *types.Signature) string {
+	s := "("
+	params := t.Params()
+	for i := 0; i < params.Len(); i++ {
+		if i != 0 {
+			s += ", "
+		}
+		pt := params.At(i).Type()
+		if t.Variadic() && i == params.Len()-1 && p.Mode&proto.VariadicSlice == 0 {
+			s += "..." + p.typeString(pt.(*types.Slice).Elem())
+		} else {
+			s += p.typeString(pt)
+		}
+	}
+	s += ")"
+	results := t.Results()
+	switch results.Len() {
+	case 0:
+		return s
+	case 1:
+		return s + " " + p.typeString(results.At(0).Type())
+	}
+	return s + " (" + p.typeString(results) + ")"
+}
+
+func (p printer) structString(t *types.Struct) string {
+	if p.Mode&proto.FoldStruct != 0 {
+		return "struct {...}"
+	}
+	if t.NumFields() == 0 {
+		return "struct {}"
+	}
+	strict := p.Mode&proto.StrictStruct != 0
+	s := "struct { "
+	for i := 0; i < t.NumFields(); i++ {
+		f := t.Field(i)
+		if i != 0 {
+			s += "; "
+		}
+		if strict && f.Embedded() {
+			s += p.typeString(f.Type())
+		} else {
+			if strict && !f.Exported() {
+				s += p.qualify(f.Pkg().Path(), f.Name())
+			} else {
+				s += f.Name()
+			}
+			s += " " + p.typeString(f.Type())
+		}
+		if tag := t.Tag(i); strict && tag != "" {
+			s += " " + strconv.Quote(tag)
+		}
+	}
+	return s + " }"
+}
+
+func (p printer) interfaceString(t *types.Interface) string {
+	if t.NumMethods() == 0 {
+		if p.Mode&proto.KeepInterface != 0 {
+			return "interface {}"
+		}
+		return "interface{}"
+	}
+	ms := make([]*types.Func, t.NumMethods())
+	for i := range ms {
+		ms[i] = t.Method(i)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Name() < ms[j].Name() })
+	s := "interface { "
+	for i, m := range ms {
+		if i != 0 {
+			s += "; "
+		}
+		name := m.Name()
+		if !m.Exported() {
+			name = p.qualify(m.Pkg().Path(), name)
+		}
+		s += name + p.signature(m.Type().(*types.Signature))
+	}
+	return s + " }"
+}
@@ -0,0 +1,233 @@
+package static_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gohub/typeless/proto"
+	"github.com/gohub/typeless/proto/static"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"html/template"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+type Base struct {
+	ID int
+}
+
+func (b Base) Key() int { return b.ID }
+
+type User struct {
+	Base
+	Name  string `json:"name"`
+	Tags  []string
+	email string
+}
+
+func (u *User) SetName(name string, more ...string) error { return nil }
+
+type (
+	IDs         []int
+	Names       map[string]IDs
+	HandlerFn   func(http.ResponseWriter, *http.Request) error
+	Events      chan<- IDs
+	List[T any] struct {
+		Items []T
+	}
+	Pair[K comparable, V any] struct {
+		Key K
+		Val V
+	}
+)
+
+var Builtins = []interface{}{
+	float32(1), int8(1), uint64(1), complex64(1), uintptr(0), unsafe.Pointer(nil),
+	true, byte('1'), "string", rune('世'), 1,
+	[1]string{}, []string{}, map[string]int{}, make(chan int), make(<-chan []int),
+	func(i int) error { return nil }, func(...int) (int, error) { return 0, nil },
+	proto.T{}, errors.New,
+}
+
+var Funs = []interface{}{
+	errors.New, fmt.Println, reflect.TypeOf, proto.Type, func(io.Reader, ...[]byte) {},
+}
+
+// 与 proto 的 canonical.golden 同类的命名类型和匿名类型
+var Named = []interface{}{
+	IDs{}, Names{}, HandlerFn(nil), Events(nil), []*IDs{},
+	reflect.Ptr, time.Second, http.Header{}, http.HandlerFunc(nil), template.HTML(""),
+	List[int]{}, List[User]{}, List[map[string]*User]{}, List[List[IDs]]{},
+	Pair[string, []*http.Request]{}, Pair[Events, func(IDs) error]{},
+	List[struct{ A IDs }]{}, List[chan<- http.Header]{}, List[interface{}]{},
+	struct{}{}, List[struct{}]{}, User{}, &User{},
+	(*interface {
+		Read([]byte) (int, error)
+		Close() error
+	})(nil),
+	List[interface{ String() string }]{},
+	struct {
+		io.Reader
+		A string `json:"a"`
+		a string
+	}{},
+	List[struct {
+		A int `json:"a"`
+	}]{},
+	List[struct {
+		*http.Cookie
+		a int
+	}]{},
+	List[func(...int) interface{}]{},
+	List[interface{ m() }]{},
+}
+
+// 类型检查较慢, 只进行一次
+var staticCorpus map[string][]types.Type
+
+// 静态类型检查本包的测试代码, 返回 Builtins, Funs, Named 中元素的静态类型
+func corpusTypes(T *testing.T) map[string][]types.Type {
+	if staticCorpus != nil {
+		return staticCorpus
+	}
+	fset := token.NewFileSet()
+	names, err := filepath.Glob("*_test.go")
+	if err != nil {
+		T.Fatal(err)
+	}
+	files := make([]*ast.File, 0, len(names))
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			T.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err = conf.Check("github.com/gohub/typeless/proto/static_test", fset, files, info); err != nil {
+		T.Fatal(err)
+	}
+
+	corpus := map[string][]types.Type{}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if ok && len(spec.Names) == 1 && len(spec.Values) == 1 {
+				collect(corpus, info, spec.Names[0], spec.Values[0])
+			}
+			return true
+		})
+	}
+	staticCorpus = corpus
+	return corpus
+}
+
+func collect(corpus map[string][]types.Type, info *types.Info, lhs, rhs ast.Expr) {
+	id, ok := lhs.(*ast.Ident)
+	if !ok || id.Name != "Builtins" && id.Name != "Funs" && id.Name != "Named" {
+		return
+	}
+	lit, ok := rhs.(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	for _, elt := range lit.Elts {
+		corpus[id.Name] = append(corpus[id.Name], info.Types[elt].Type)
+	}
+}
+
+// 遍历语料中静态类型不是接口的元素, 运行时描述的是接口值的动态类型
+func eachType(T *testing.T, fn func(name string, i int, v interface{}, t types.Type)) {
+	corpus := corpusTypes(T)
+	for name, values := range map[string][]interface{}{
+		"Builtins": Builtins, "Funs": Funs, "Named": Named,
+	} {
+		ts := corpus[name]
+		if len(ts) != len(values) {
+			T.Fatalf("%s: want %d elements, got %d", name, len(values), len(ts))
+		}
+		for i, t := range ts {
+			if t == nil {
+				T.Errorf("%s[%d]: no static type", name, i)
+				continue
+			}
+			if !types.IsInterface(t) {
+				fn(name, i, values[i], t)
+			}
+		}
+	}
+}
+
+func TestType(T *testing.T) {
+	eachType(T, func(name string, i int, v interface{}, t types.Type) {
+		if want, got := proto.Type(v), static.Type(t); got != want {
+			T.Errorf("%s[%d] want: %s\n got: %s", name, i, want, got)
+		}
+	})
+}
+
+func TestPrint(T *testing.T) {
+	for _, mode := range []proto.Mode{proto.KeepInterface, proto.FoldStruct, proto.VariadicSlice, proto.StrictStruct} {
+		p := &proto.Printer{Mode: mode}
+		eachType(T, func(name string, i int, v interface{}, t types.Type) {
+			if want, got := p.Type(v), static.Print(p, t); got != want {
+				T.Errorf("mode %d %s[%d] want: %s\n got: %s", mode, name, i, want, got)
+			}
+		})
+	}
+}
+
+func TestDescribe(T *testing.T) {
+	eachType(T, func(name string, i int, v interface{}, t types.Type) {
+		if name != "Named" {
+			return
+		}
+		want, got := proto.Describe(v), static.Describe(t)
+		if got.String() != want.String() {
+			T.Errorf("Named[%d] want: %s\n got: %s", i, want, got)
+		}
+		ws, ok := want.(*proto.Struct)
+		if !ok {
+			return
+		}
+		gs, ok := got.(*proto.Struct)
+		if !ok || len(gs.Fields) != len(ws.Fields) || len(gs.Methods) != len(ws.Methods) {
+			T.Errorf("Named[%d] want: %#v\n got: %#v", i, want, got)
+			return
+		}
+		for j, f := range ws.Fields {
+			g := gs.Fields[j]
+			if g.Name != f.Name || g.Proto != f.Proto || g.Tag != f.Tag ||
+				g.Embedded != f.Embedded || g.Exported != f.Exported {
+				T.Errorf("Named[%d] field want: %#v\n got: %#v", i, f, g)
+			}
+		}
+	})
+}
+
+func TestMethods(T *testing.T) {
+	var user types.Type
+	eachType(T, func(name string, i int, v interface{}, t types.Type) {
+		if _, ok := v.(User); ok {
+			user = t
+		}
+	})
+	want, got := proto.Methods(&User{}), static.Methods(user)
+	if len(got) != len(want) {
+		T.Fatalf("want: %v\n got: %v", want, got)
+	}
+	for i := range want {
+		if got[i].String() != want[i].String() {
+			T.Errorf("want: %s\n got: %s", want[i].String(), got[i].String())
+		}
+	}
+}
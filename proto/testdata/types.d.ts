@@ -0,0 +1,40 @@
+declare namespace github.com.gohub.typeless.proto_test {
+	interface Address {
+		city: string;
+		zip?: string;
+	}
+
+	type IDs = number[];
+
+	type Names = Record<string, github.com.gohub.typeless.proto_test.IDs>;
+
+	interface Page_github_com_gohub_typeless_proto_test_Address {
+		items: github.com.gohub.typeless.proto_test.Address[];
+		next?: number | null;
+		meta: { total: number; };
+	}
+
+	interface Person {
+		name: string;
+		Nick?: string;
+		age: string;
+		home?: github.com.gohub.typeless.proto_test.Address | null;
+		addrs: github.com.gohub.typeless.proto_test.Address[];
+		avatar: string;
+		scores: Record<string, number>;
+		pair: boolean[];
+		extra: any;
+		born: string;
+		friends: (github.com.gohub.typeless.proto_test.Person | null)[];
+		ID: number;
+	}
+}
+
+declare namespace io {
+	interface ReadCloser {
+		Close(): Error;
+		Read(a0: string): [number, Error];
+	}
+}
+
+declare function check(a0: string, ...a1: number[]): [boolean, Error];
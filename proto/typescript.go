@@ -0,0 +1,371 @@
+package proto
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 生成描述对应的 TypeScript 声明 (.d.ts), 参数可以是描述, 值或者 reflect.Type.
+// 规则如下
+//   - 命名 struct 对应 interface, 属性名按 encoding/json 的规则使用 json tag,
+//     omitempty 和指针字段是可选属性, 未命名的嵌入 struct 展开到外层
+//   - 其他命名类型对应 type 别名, 引用的命名类型一并生成
+//   - PkgPath 对应 namespace, 例如 example.com/x.User 为 example.com.x.User,
+//     不是标识符的部分替换为 _
+//   - bool 为 boolean, 整数和浮点数为 number, string 和 []byte 为 string,
+//     slice 和 array 为 T[], map 为 Record<string, T>, 指针为 T | null,
+//     time.Time 和实现了 encoding.TextMarshaler 的类型为 string, interface 为 any, error 为 Error
+//   - *Interface 对应包含方法的 interface, *Fn 对应 function 声明, 多个返回值为元组
+//
+// 描述中引用的命名类型由 Default 查找. 数据中的 complex, chan, func 返回错误.
+func TypeScript(xs ...interface{}) (string, error) {
+	e := &tsExporter{decls: map[string]map[string]string{}}
+	var funcs []string
+	for _, x := range xs {
+		p, r := newResolver(x)
+		e.resolver = r
+		switch p := p.(type) {
+		case *Fn:
+			if p.Name == "" {
+				return "", toExportFailed("TypeScript function needs a name: ", p.Proto)
+			}
+			sig, err := e.signature(p.Name, p)
+			if err != nil {
+				return "", err
+			}
+			funcs = append(funcs, "declare function "+tsIdent(p.Name)+sig+";\n")
+		case *Struct:
+			if p.Name == "" {
+				return "", toExportFailed("TypeScript struct needs a name: ", p.Proto)
+			}
+			if err := e.declare(p.Proto, func(name string) (string, error) {
+				return e.iface(p.Name, name, p.Fields)
+			}); err != nil {
+				return "", err
+			}
+		case *Interface:
+			if p.Name == "" {
+				return "", toExportFailed("TypeScript interface needs a name: ", p.Proto)
+			}
+			if err := e.declare(p.Proto, func(name string) (string, error) {
+				return e.methods(name, p.Methods)
+			}); err != nil {
+				return "", err
+			}
+		case *T:
+			if _, err := e.typeOf(p.Proto, p.Proto); err != nil {
+				return "", err
+			}
+		default:
+			return "", toExportFailed("TypeScript does not support ", protoOf(p))
+		}
+	}
+	return e.source(funcs), nil
+}
+
+type tsExporter struct {
+	*resolver
+	decls map[string]map[string]string // namespace -> 名称 -> 声明, 生成中的声明为空
+}
+
+// 生成命名类型 s 的声明, decl 的参数是声明中使用的名称
+func (e *tsExporter) declare(s string, decl func(name string) (string, error)) error {
+	ns, name := tsName(s)
+	if e.decls[ns] == nil {
+		e.decls[ns] = map[string]string{}
+	}
+	if _, ok := e.decls[ns][name]; ok {
+		return nil
+	}
+	e.decls[ns][name] = ""
+	d, err := decl(name)
+	if err != nil {
+		return err
+	}
+	e.decls[ns][name] = d
+	return nil
+}
+
+// 命名类型的 namespace 和名称
+func tsName(s string) (ns, name string) {
+	x, err := Parse(s)
+	if err != nil || x.Kind != KindIdent {
+		return "", tsIdent(s)
+	}
+	if len(x.Args) == 0 {
+		name = tsIdent(x.Name)
+	} else {
+		name = tsIdent(x.Name + "_" + x.String()[len(x.PkgPath)+len(x.Name)+1:])
+	}
+	if x.PkgPath == "" {
+		return "", name
+	}
+	elems := strings.FieldsFunc(x.PkgPath, func(r rune) bool { return r == '/' || r == '.' })
+	for i := range elems {
+		elems[i] = tsIdent(elems[i])
+	}
+	return strings.Join(elems, "."), name
+}
+
+// 替换不能出现在标识符中的字符
+func tsIdent(s string) string {
+	b := []byte(s)
+	n := 0
+	for _, c := range b {
+		if c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b[n] = c
+		} else if n != 0 && b[n-1] == '_' {
+			continue
+		} else {
+			b[n] = '_'
+		}
+		n++
+	}
+	s = strings.Trim(string(b[:n]), "_")
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
+
+// 引用命名类型 s 时的写法
+func tsRef(s string) string {
+	ns, name := tsName(s)
+	if ns == "" {
+		return name
+	}
+	return ns + "." + name
+}
+
+// 属性名不是标识符时加上引号
+func tsProp(name string) string {
+	if name != "" && tsIdent(name) == name {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func (e *tsExporter) source(funcs []string) string {
+	var b strings.Builder
+	nss := make([]string, 0, len(e.decls))
+	for ns := range e.decls {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+	for _, ns := range nss {
+		names := make([]string, 0, len(e.decls[ns]))
+		for name := range e.decls[ns] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if b.Len() != 0 {
+			b.WriteByte('\n')
+		}
+		if ns == "" {
+			for i, name := range names {
+				if i != 0 {
+					b.WriteByte('\n')
+				}
+				b.WriteString("declare " + e.decls[ns][name])
+			}
+			continue
+		}
+		b.WriteString("declare namespace " + ns + " {\n")
+		for i, name := range names {
+			if i != 0 {
+				b.WriteByte('\n')
+			}
+			for _, line := range strings.SplitAfter(e.decls[ns][name], "\n") {
+				if line != "" {
+					b.WriteString("\t" + line)
+				}
+			}
+		}
+		b.WriteString("}\n")
+	}
+	if len(funcs) != 0 && b.Len() != 0 {
+		b.WriteByte('\n')
+	}
+	for _, f := range funcs {
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+// struct 对应的 interface
+func (e *tsExporter) iface(path, name string, fields []Field) (string, error) {
+	s := "interface " + name + " {\n"
+	for _, p := range e.jsonProps(path, fields) {
+		t, err := e.field(p)
+		if err != nil {
+			return "", err
+		}
+		s += "\t" + tsProp(p.name)
+		if p.omitempty || strings.HasPrefix(p.Proto, "*") {
+			s += "?"
+		}
+		s += ": " + t + ";\n"
+	}
+	return s + "}\n", nil
+}
+
+func (e *tsExporter) field(p jsonProp) (string, error) {
+	if p.asString {
+		return "string", nil
+	}
+	return e.typeOf(p.path, p.Proto)
+}
+
+// Go 接口对应的 interface
+func (e *tsExporter) methods(name string, m map[string]Fn) (string, error) {
+	s := "interface " + name + " {\n"
+	for _, n := range methodNames(m) {
+		fn := m[n]
+		sig, err := e.signature(name+"."+n, &fn)
+		if err != nil {
+			return "", err
+		}
+		s += "\t" + tsProp(n) + sig + ";\n"
+	}
+	return s + "}\n", nil
+}
+
+// 函数签名 (a0: T, ...a1: U[]): R
+func (e *tsExporter) signature(path string, fn *Fn) (string, error) {
+	s := "("
+	for i, t := range fn.In {
+		if i != 0 {
+			s += ", "
+		}
+		ts, err := e.typeOf(path, t.Proto)
+		if err != nil {
+			return "", err
+		}
+		if fn.Variadic && i == len(fn.In)-1 {
+			s += "..."
+		}
+		s += "a" + strconv.Itoa(i) + ": " + ts
+	}
+	s += "): "
+	outs := make([]string, len(fn.Out))
+	for i, t := range fn.Out {
+		ts, err := e.typeOf(path, t.Proto)
+		if err != nil {
+			return "", err
+		}
+		outs[i] = ts
+	}
+	switch len(outs) {
+	case 0:
+		return s + "void", nil
+	case 1:
+		return s + outs[0], nil
+	}
+	return s + "[" + strings.Join(outs, ", ") + "]", nil
+}
+
+func (e *tsExporter) typeOf(path, s string) (string, error) {
+	x, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return e.expr(path, x)
+}
+
+func (e *tsExporter) expr(path string, x *Expr) (string, error) {
+	switch x.Kind {
+	case KindNil:
+		return "null", nil
+	case KindIdent:
+		return e.ident(path, x)
+	case KindPtr:
+		elem, err := e.expr(path, x.Elem)
+		if err != nil {
+			return "", err
+		}
+		return elem + " | null", nil
+	case KindSlice, KindArray:
+		if x.Kind == KindSlice && x.Elem.Kind == KindIdent && x.Elem.PkgPath == "" && x.Elem.Name == "uint8" {
+			return "string", nil
+		}
+		elem, err := e.expr(path+"[]", x.Elem)
+		if err != nil {
+			return "", err
+		}
+		if strings.ContainsAny(elem, " |") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]", nil
+	case KindMap:
+		elem, err := e.expr(path+"[]", x.Elem)
+		if err != nil {
+			return "", err
+		}
+		return "Record<string, " + elem + ">", nil
+	case KindStruct:
+		s, err := e.iface(path, "", exprFields(x))
+		if err != nil {
+			return "", err
+		}
+		// 匿名 struct 写为对象类型, 例如 { A: number; }
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "interface  "), "\n")
+		return strings.NewReplacer("\n\t", " ", "\n", " ").Replace(s), nil
+	case KindInterface:
+		return "any", nil
+	}
+	return "", toExportFailed(path, ": unsupported type ", x)
+}
+
+func (e *tsExporter) ident(path string, x *Expr) (string, error) {
+	if x.PkgPath == "" {
+		switch x.Name {
+		case "bool":
+			return "boolean", nil
+		case "string":
+			return "string", nil
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+			"float32", "float64":
+			return "number", nil
+		case "error":
+			return "Error", nil
+		}
+		return "", toExportFailed(path, ": unsupported type ", x)
+	}
+	s := x.String()
+	t, err := e.lookup(s)
+	if err != nil {
+		return "", toExportFailed(path, ": ", err)
+	}
+	pt := reflect.PointerTo(t)
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return "string", nil
+	case t.Implements(jsonMarshaler) || pt.Implements(jsonMarshaler):
+		return "any", nil
+	case t.Implements(textMarshaler) || pt.Implements(textMarshaler):
+		return "string", nil
+	case t.Kind() == reflect.Interface:
+		return "any", nil
+	}
+	err = e.declare(s, func(name string) (string, error) {
+		if t.Kind() == reflect.Struct {
+			return e.iface(typeName(t), name, structFields(t))
+		}
+		u := underlying(t)
+		if u == "" {
+			return "", toExportFailed(path, ": unsupported type ", s)
+		}
+		ts, err := e.typeOf(path, u)
+		if err != nil {
+			return "", err
+		}
+		return "type " + name + " = " + ts + ";\n", nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return tsRef(s), nil
+}
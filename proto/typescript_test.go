@@ -0,0 +1,71 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type Page[T any] struct {
+	Items []T  `json:"items"`
+	Next  *int `json:"next"`
+	Meta  struct {
+		Total int `json:"total"`
+	} `json:"meta"`
+}
+
+func TestTypeScript(T *testing.T) {
+	src, err := proto.TypeScript(
+		Person{}, Page[Address]{}, Names{},
+		proto.Describe(proto.TypeIndirect((*io.ReadCloser)(nil))),
+		proto.Describe((&User{}).SetName),
+	)
+	if err == nil || src != "" {
+		T.Fatalf("want an error for unnamed func, got %v\n%s", err, src)
+	}
+	fn := proto.Describe(func(string, ...int) (bool, error) { return false, nil }).(*proto.Fn)
+	fn.Name = "check"
+	src, err = proto.TypeScript(
+		Person{}, Page[Address]{}, Names{},
+		proto.Describe(proto.TypeIndirect((*io.ReadCloser)(nil))), fn,
+	)
+	if err != nil {
+		T.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "types.d.ts")
+	if *update {
+		if err = os.WriteFile(golden, []byte(src), 0644); err != nil {
+			T.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		T.Fatal(err)
+	}
+	if src != string(want) {
+		T.Errorf("want:\n%s\n got:\n%s", want, src)
+	}
+}
+
+func TestTypeScriptSelf(T *testing.T) {
+	src, err := proto.TypeScript(Self{})
+	if err != nil {
+		T.Fatal(err)
+	}
+	if !strings.Contains(src, "interface Self {\n\t\tx: number;\n\t}") {
+		T.Errorf("want property x only, got:\n%s", src)
+	}
+}
+
+func TestTypeScriptError(T *testing.T) {
+	for _, x := range []interface{}{
+		struct{ C chan int }{}, struct{ X complex64 }{},
+	} {
+		if _, err := proto.TypeScript(x); err == nil {
+			T.Errorf("want an error for %s", proto.Type(x))
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package proto
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// 两个值的一处差异. Path 是差异的位置, 例如 .Items[3].(example.com/x.Item).Price,
+// 顶层为空. A, B 是两个值的写法, 动态类型不同时是 proto 描述, 不存在时为空.
+type ValueChange struct {
+	Path string `json:"path"`
+	A    string `json:"a,omitempty"`
+	B    string `json:"b,omitempty"`
+}
+
+// 文本形式, 不存在的值写为 <missing>, 例如
+//
+//	.Items[3].(example.com/x.Item).Price: 1 != 2
+//	.Tags[2]: <missing> != "new"
+func (c ValueChange) String() string {
+	a, b := c.A, c.B
+	if a == "" {
+		a = "<missing>"
+	}
+	if b == "" {
+		b = "<missing>"
+	}
+	if c.Path == "" {
+		return a + " != " + b
+	}
+	return c.Path + ": " + a + " != " + b
+}
+
+// 差异列表
+type ValueChanges []ValueChange
+
+// 文本报告, 每行一处差异
+func (cs ValueChanges) String() string {
+	s := ""
+	for _, c := range cs {
+		s += c.String() + "\n"
+	}
+	return s
+}
+
+// 比较两个值, 返回全部差异, 相等时返回 nil. 规则如下
+//   - struct 逐个比较字段, 包括非导出字段
+//   - map 按 key 的值排序比较, 与 Literal 一致
+//   - slice 以最长公共子序列对齐, 插入和删除的元素与另一方的 <missing> 比较
+//   - 指针比较指向的值, 已经在比较中的指针对视为相等, 以处理循环引用
+//   - interface 动态类型不同时以 proto 描述报告, 相同时路径加上 .(proto)
+//   - func 只比较是否是同一个函数
+func ValueDiff(a, b interface{}) ValueChanges {
+	d := &valueDiffer{seen: map[[2]visit]bool{}}
+	d.diff("", ValueOf(a), ValueOf(b))
+	return d.cs
+}
+
+type valueDiffer struct {
+	cs   ValueChanges
+	seen map[[2]visit]bool
+}
+
+func (d *valueDiffer) add(path, a, b string) {
+	d.cs = append(d.cs, ValueChange{path, a, b})
+}
+
+// 以写法报告差异
+func (d *valueDiffer) changed(path string, a, b reflect.Value) {
+	d.add(path, valueLiteral(a), valueLiteral(b))
+}
+
+func (d *valueDiffer) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.changed(path, a, b)
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.add(path, prototype(a.Type()), prototype(b.Type()))
+		return
+	}
+	switch a.Kind() {
+	case reflect.Bool:
+		if a.Bool() != b.Bool() {
+			d.changed(path, a, b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a.Int() != b.Int() {
+			d.changed(path, a, b)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a.Uint() != b.Uint() {
+			d.changed(path, a, b)
+		}
+	case reflect.Float32, reflect.Float64:
+		if a.Float() != b.Float() {
+			d.changed(path, a, b)
+		}
+	case reflect.Complex64, reflect.Complex128:
+		if a.Complex() != b.Complex() {
+			d.changed(path, a, b)
+		}
+	case reflect.String:
+		if a.String() != b.String() {
+			d.changed(path, a, b)
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if a.Pointer() != b.Pointer() {
+			d.changed(path, a, b)
+		}
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.changed(path, a, b)
+			}
+			return
+		}
+		a, b = a.Elem(), b.Elem()
+		if a.Type() != b.Type() {
+			d.add(path, prototype(a.Type()), prototype(b.Type()))
+			return
+		}
+		d.diff(path+".("+prototype(a.Type())+")", a, b)
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.changed(path, a, b)
+			}
+			return
+		}
+		if a.Pointer() == b.Pointer() || !d.enter(a, b) {
+			return
+		}
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			d.diff(path+"."+t.Field(i).Name, a.Field(i), b.Field(i))
+		}
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			d.diff(path+"["+strconv.Itoa(i)+"]", a.Index(i), b.Index(i))
+		}
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() {
+			d.changed(path, a, b)
+			return
+		}
+		if a.Pointer() == b.Pointer() && a.Len() == b.Len() || !d.enter(a, b) {
+			return
+		}
+		d.slice(path, a, b)
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			d.changed(path, a, b)
+			return
+		}
+		if a.Pointer() == b.Pointer() || !d.enter(a, b) {
+			return
+		}
+		d.mapOf(path, a, b)
+	}
+}
+
+// 记录正在比较的引用对, 已经在比较中时返回 false
+func (d *valueDiffer) enter(a, b reflect.Value) bool {
+	key := [2]visit{{a.Pointer(), a.Type()}, {b.Pointer(), b.Type()}}
+	if d.seen[key] {
+		return false
+	}
+	d.seen[key] = true
+	return true
+}
+
+// LCS 对齐的规模上限, 超过时按下标比较
+const maxLCS = 1 << 20
+
+func (d *valueDiffer) slice(path string, a, b reflect.Value) {
+	n, m := a.Len(), b.Len()
+	index := func(i int) string { return path + "[" + strconv.Itoa(i) + "]" }
+	if n*m > maxLCS {
+		for i := 0; i < n || i < m; i++ {
+			switch {
+			case i >= m:
+				d.add(index(i), valueLiteral(a.Index(i)), "")
+			case i >= n:
+				d.add(index(i), "", valueLiteral(b.Index(i)))
+			default:
+				d.diff(index(i), a.Index(i), b.Index(i))
+			}
+		}
+		return
+	}
+	// lcs[i][j] 是 a[i:] 与 b[j:] 的最长公共子序列长度
+	eq := make([][]bool, n)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		eq[i] = make([]bool, m)
+		for j := m - 1; j >= 0; j-- {
+			eq[i][j] = d.equal(a.Index(i), b.Index(j))
+			if eq[i][j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	// 两对相等元素之间的部分逐个比较, 多出的元素是删除或插入
+	i, j := 0, 0
+	for i < n || j < m {
+		i0, j0 := i, j
+		for (i < n || j < m) && !(i < n && j < m && eq[i][j]) {
+			if j == m || i < n && lcs[i+1][j] >= lcs[i][j+1] {
+				i++
+			} else {
+				j++
+			}
+		}
+		for ; i0 < i && j0 < j; i0, j0 = i0+1, j0+1 {
+			d.diff(index(i0), a.Index(i0), b.Index(j0))
+		}
+		for ; i0 < i; i0++ {
+			d.add(index(i0), valueLiteral(a.Index(i0)), "")
+		}
+		for ; j0 < j; j0++ {
+			d.add(index(j0), "", valueLiteral(b.Index(j0)))
+		}
+		i, j = i+1, j+1
+	}
+}
+
+// 判断两个值是否没有差异
+func (d *valueDiffer) equal(a, b reflect.Value) bool {
+	e := &valueDiffer{seen: map[[2]visit]bool{}}
+	e.diff("", a, b)
+	return len(e.cs) == 0
+}
+
+func (d *valueDiffer) mapOf(path string, a, b reflect.Value) {
+	type entry struct {
+		s   string
+		key reflect.Value
+	}
+	keys := map[string]entry{}
+	for _, m := range []reflect.Value{a, b} {
+		for _, k := range m.MapKeys() {
+			s := valueLiteral(k)
+			keys[s] = entry{s, k}
+		}
+	}
+	sorted := make([]entry, 0, len(keys))
+	for _, e := range keys {
+		sorted = append(sorted, e)
+	}
+	// 与 Literal 一致按 key 的值排序, 值相等时 (比如 NaN) 按写法排序
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := compareValue(sorted[i].key, sorted[j].key); c != 0 {
+			return c < 0
+		}
+		return sorted[i].s < sorted[j].s
+	})
+	for _, e := range sorted {
+		p := path + "[" + e.s + "]"
+		x, y := a.MapIndex(e.key), b.MapIndex(e.key)
+		switch {
+		case !x.IsValid():
+			d.add(p, "", valueLiteral(y))
+		case !y.IsValid():
+			d.add(p, valueLiteral(x), "")
+		default:
+			d.diff(p, x, y)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package proto_test
+
+import (
+	"github.com/gohub/typeless/proto"
+	"testing"
+)
+
+type item struct {
+	Name  string
+	Price int
+}
+
+type order struct {
+	Items []interface{}
+	Tags  []string
+	Attrs map[string]int
+	Next  *order
+	note  string
+}
+
+func TestValueDiff(T *testing.T) {
+	a := &order{
+		Items: []interface{}{item{"a", 1}, item{"b", 1}, 3, nil},
+		Tags:  []string{"x", "y", "z"},
+		Attrs: map[string]int{"k": 1, "old": 2},
+		note:  "n",
+	}
+	b := &order{
+		Items: []interface{}{item{"a", 1}, item{"b", 2}, "3", IDs{1}},
+		Tags:  []string{"w", "x", "z"},
+		Attrs: map[string]int{"k": 2, "new": 3},
+		note:  "m",
+	}
+	a.Next, b.Next = a, b
+	want := `.Items[1].(github.com/gohub/typeless/proto_test.item).Price: 1 != 2
+.Items[2]: int != string
+.Items[3]: nil != github.com/gohub/typeless/proto_test.IDs{1}
+.Tags[0]: <missing> != "w"
+.Tags[1]: "y" != <missing>
+.Attrs["k"]: 1 != 2
+.Attrs["new"]: <missing> != 3
+.Attrs["old"]: 2 != <missing>
+.note: "n" != "m"
+`
+	if got := proto.ValueDiff(a, b).String(); got != want {
+		T.Errorf("want:\n%s\n got:\n%s", want, got)
+	}
+	if cs := proto.ValueDiff(a, a); cs != nil {
+		T.Errorf("want no changes, got %v", cs)
+	}
+	// key 按值排序
+	if got, want := proto.ValueDiff(map[int]int{1: 1, 2: 2, 10: 10}, map[int]int{1: 0, 2: 0, 10: 0}).String(),
+		"[1]: 1 != 0\n[2]: 2 != 0\n[10]: 10 != 0\n"; got != want {
+		T.Errorf("want:\n%s\n got:\n%s", want, got)
+	}
+	if got := proto.ValueDiff(1, "1").String(); got != "int != string\n" {
+		T.Errorf("got %q", got)
+	}
+	if got := proto.ValueDiff([]*item{{"a", 1}}, []*item{nil}).String(); got != `[0]: &github.com/gohub/typeless/proto_test.item{Name: "a", Price: 1} != nil`+"\n" {
+		T.Errorf("got %q", got)
+	}
+}